@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// The schema is recursive (token -> holders -> account -> balances -> ...), so queries are bounded
+// before they run: by nesting depth, and by the number of fields they may resolve, counting each
+// connection as returning first items, defaultPageLimit if first is not set.
+const (
+	maxQueryDepth      = 12
+	maxQueryComplexity = 5000
+)
+
+type queryCost struct {
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	// spreading guards against fragment cycles, the validation rejects them later
+	spreading map[string]bool
+}
+
+// checkQueryCost returns an error if the operation of req is too deep or too complex.
+// Queries that do not parse are left to graphql.Do to report.
+func checkQueryCost(schema graphql.Schema, req *graphQLRequest) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return nil
+	}
+	cost := &queryCost{
+		variables: req.Variables,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		spreading: make(map[string]bool),
+	}
+	var operations []*ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			operations = append(operations, definition)
+		case *ast.FragmentDefinition:
+			cost.fragments[definition.Name.Value] = definition
+		}
+	}
+	for _, operation := range operations {
+		if req.OperationName != "" && (operation.Name == nil || operation.Name.Value != req.OperationName) {
+			continue
+		}
+		depth, complexity := cost.selections(schema.QueryType(), operation.SelectionSet, 1)
+		if depth > maxQueryDepth {
+			return fmt.Errorf("query depth %d exceeds %d", depth, maxQueryDepth)
+		}
+		if complexity > maxQueryComplexity {
+			return fmt.Errorf("query complexity %d exceeds %d", complexity, maxQueryComplexity)
+		}
+	}
+	return nil
+}
+
+// selections returns the depth of set and the number of fields it resolves when it is resolved
+// multiplier times
+func (c *queryCost) selections(parent *graphql.Object, set *ast.SelectionSet, multiplier int) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+	depth, complexity := 0, 0
+	add := func(d int, n int) {
+		if d > depth {
+			depth = d
+		}
+		complexity += n
+		if complexity > maxQueryComplexity {
+			complexity = maxQueryComplexity + 1
+		}
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			add(c.field(parent, selection, multiplier))
+		case *ast.InlineFragment:
+			add(c.selections(parent, selection.SelectionSet, multiplier))
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || c.spreading[name] {
+				continue
+			}
+			c.spreading[name] = true
+			add(c.selections(parent, fragment.SelectionSet, multiplier))
+			c.spreading[name] = false
+		}
+	}
+	return depth, complexity
+}
+
+func (c *queryCost) field(parent *graphql.Object, field *ast.Field, multiplier int) (int, int) {
+	var definition *graphql.FieldDefinition
+	if parent != nil {
+		definition = parent.Fields()[field.Name.Value]
+	}
+	if definition == nil {
+		// introspection and unknown fields, the latter fail validation
+		return 1, multiplier
+	}
+	childMultiplier := multiplier
+	for _, arg := range definition.Args {
+		if arg.PrivateName == "first" {
+			childMultiplier *= c.pageSize(field)
+			if childMultiplier > maxQueryComplexity {
+				childMultiplier = maxQueryComplexity + 1
+			}
+			break
+		}
+	}
+	child, _ := graphql.GetNamed(definition.Type).(*graphql.Object)
+	depth, complexity := c.selections(child, field.SelectionSet, childMultiplier)
+	return depth + 1, multiplier + complexity
+}
+
+// pageSize returns the first argument of a connection field, at least 1 so nested fields count
+func (c *queryCost) pageSize(field *ast.Field) int {
+	size := defaultPageLimit
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		var value interface{}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			value = v.Value
+		case *ast.Variable:
+			value = c.variables[v.Name.Value]
+		}
+		switch v := value.(type) {
+		case string:
+			fmt.Sscan(v, &size)
+		case float64:
+			size = int(v)
+		case int:
+			size = v
+		}
+	}
+	if size < 1 {
+		return 1
+	}
+	if size > maxPageLimit {
+		// connectionArgs rejects it
+		return maxPageLimit
+	}
+	return size
+}
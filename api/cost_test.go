@@ -0,0 +1,40 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckQueryCost(t *testing.T) {
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		err       string
+	}{
+		{"readme example", `{ tokens(first: 10, orderBy: HOLDERS, orderDirection: DESC) { totalCount
+			edges { node { tick minted holders(first: 5) { edges { node { address balance account { balances { tick amount } } } } } } } } }`, nil, ""},
+		{"nested connections", `{ tokens(first: 100) { edges { node { holders(first: 100) { edges { node { address } } } } } } }`, nil, "complexity"},
+		{"first from a variable", `query q($n: Int) { tokens(first: $n) { edges { node { holders(first: $n) { edges { node { address } } } } } } }`,
+			map[string]interface{}{"n": float64(100)}, "complexity"},
+		{"small variable", `query q($n: Int) { tokens(first: $n) { edges { node { holders(first: $n) { edges { node { address } } } } } } }`,
+			map[string]interface{}{"n": float64(5)}, ""},
+		{"recursion through accounts", `{ token(tick: "rose") { holders(first: 1) { edges { node { account { balances { token {
+			holders(first: 1) { edges { node { account { balances { token { tick } } } } } } } } } } } } } }`, nil, "depth"},
+		{"fragments count", `{ tokens(first: 100) { edges { node { ...h } } } } fragment h on Token { holders(first: 100) { edges { node { address } } } }`, nil, "complexity"},
+		{"fragment cycle", `{ tokens { edges { node { ...a } } } } fragment a on Token { ...b } fragment b on Token { ...a }`, nil, ""},
+		{"syntax errors are left to graphql", `{ tokens {`, nil, ""},
+	}
+	for _, tt := range tests {
+		err := checkQueryCost(schema, &graphQLRequest{Query: tt.query, Variables: tt.variables})
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: err = %v, want %s", tt.name, err, tt.err)
+		}
+	}
+}
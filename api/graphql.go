@@ -0,0 +1,588 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// The GraphQL endpoint serves the same state as the REST routes through core's query functions.
+// Lists are relay style connections: pass first and the endCursor of the previous page as after.
+// Cursors are offsets into the list, not keys of its items. Operations, and tokens in deploy order, only grow
+// at the end, but holders, listings and the other token orders change with the blocks, so paging through them
+// while blocks are indexed may repeat or skip items.
+
+const cursorPrefix = "cursor:"
+
+type connection struct {
+	Edges      []*edge
+	PageInfo   *pageInfo
+	TotalCount int
+}
+
+type edge struct {
+	Cursor string
+	Node   interface{}
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   string
+}
+
+type account struct {
+	Address string
+}
+
+// encodeCursor returns the cursor of the item at offset
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	return offset, nil
+}
+
+// connectionArgs reads first and after, the page starts after the item the cursor points to
+func connectionArgs(args map[string]interface{}) (int, int, error) {
+	offset, limit := 0, defaultPageLimit
+	if first, ok := args["first"].(int); ok {
+		if first < 0 || first > maxPageLimit {
+			return 0, 0, fmt.Errorf("first must be between 0 and %d", maxPageLimit)
+		}
+		limit = first
+	}
+	if after, ok := args["after"].(string); ok && after != "" {
+		n, err := decodeCursor(after)
+		if err != nil {
+			return 0, 0, err
+		}
+		offset = n + 1
+	}
+	return offset, limit, nil
+}
+
+func newConnection[T any](nodes []T, offset int, total int) *connection {
+	conn := &connection{
+		Edges:      make([]*edge, 0, len(nodes)),
+		PageInfo:   &pageInfo{},
+		TotalCount: total,
+	}
+	for i, node := range nodes {
+		conn.Edges = append(conn.Edges, &edge{Cursor: encodeCursor(offset + i), Node: node})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	conn.PageInfo.HasNextPage = offset+len(nodes) < total
+	return conn
+}
+
+// pageOf slices an already fetched list
+func pageOf[T any](all []T, offset int, limit int) []T {
+	if offset > len(all) {
+		offset = len(all)
+	}
+	end := offset + limit
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[offset:end]
+}
+
+var decimalScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Decimal",
+	Description: "A fixed-point decimal encoded as a string to keep its precision",
+	Serialize: func(value interface{}) interface{} {
+		if d, ok := value.(*model.DDecimal); ok && d != nil {
+			return d.String()
+		}
+		return nil
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+func connectionType(name string, node graphql.Output) *graphql.Object {
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: node},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewList(edgeType)},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+}
+
+func withConnectionArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	if args == nil {
+		args = graphql.FieldConfigArgument{}
+	}
+	args["first"] = &graphql.ArgumentConfig{Type: graphql.Int}
+	args["after"] = &graphql.ArgumentConfig{Type: graphql.String}
+	return args
+}
+
+func recordFilterArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"tick":    &graphql.ArgumentConfig{Type: graphql.String},
+		"address": &graphql.ArgumentConfig{Type: graphql.String},
+		"op":      &graphql.ArgumentConfig{Type: graphql.String},
+		"valid":   &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+}
+
+func resolveRecords(filter core.RecordFilter, args map[string]interface{}) (interface{}, error) {
+	offset, limit, err := connectionArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if tick, ok := args["tick"].(string); ok {
+		filter.Tick = tick
+	}
+	if address, ok := args["address"].(string); ok {
+		filter.Address = address
+	}
+	if op, ok := args["op"].(string); ok {
+		filter.Operation = model.RRC20Operation(op)
+	}
+	if valid, ok := args["valid"].(int); ok {
+		if valid < math.MinInt8 || valid > math.MaxInt8 {
+			return nil, errInvalidParam("valid")
+		}
+		code := model.ValideCode(valid)
+		filter.Valid = &code
+	}
+	records, total := core.QueryRecords(filter, offset, limit)
+	return newConnection(records, offset, total), nil
+}
+
+func resolveListings(filter core.ListingFilter, args map[string]interface{}) (interface{}, error) {
+	offset, limit, err := connectionArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if tick, ok := args["tick"].(string); ok {
+		filter.Tick = tick
+	}
+	if address, ok := args["address"].(string); ok {
+		filter.Address = address
+	}
+	listings, total := core.QueryListings(filter, offset, limit)
+	return newConnection(listings, offset, total), nil
+}
+
+func resolveToken(tick string) (interface{}, error) {
+	if token, ok := core.QueryToken(tick); ok {
+		return token, nil
+	}
+	return nil, nil
+}
+
+var tokenOrderField = graphql.NewEnum(graphql.EnumConfig{
+	Name: "TokenOrderField",
+	Values: graphql.EnumValueConfigMap{
+		"NUMBER":     &graphql.EnumValueConfig{Value: "number"},
+		"TICK":       &graphql.EnumValueConfig{Value: "tick"},
+		"PROGRESS":   &graphql.EnumValueConfig{Value: "progress"},
+		"HOLDERS":    &graphql.EnumValueConfig{Value: "holders"},
+		"TRXS":       &graphql.EnumValueConfig{Value: "trxs"},
+		"CREATED_AT": &graphql.EnumValueConfig{Value: "createdAt"},
+	},
+})
+
+var orderDirection = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderDirection",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "asc"},
+		"DESC": &graphql.EnumValueConfig{Value: "desc"},
+	},
+})
+
+func tokenLess(field string) func(a *model.Token, b *model.Token) bool {
+	switch field {
+	case "tick":
+		return func(a *model.Token, b *model.Token) bool {
+			return model.NormalizeTick(a.Tick) < model.NormalizeTick(b.Tick)
+		}
+	case "progress":
+		return func(a *model.Token, b *model.Token) bool { return a.Progress < b.Progress }
+	case "holders":
+		return func(a *model.Token, b *model.Token) bool { return a.Holders < b.Holders }
+	case "trxs":
+		return func(a *model.Token, b *model.Token) bool { return a.Trxs < b.Trxs }
+	case "createdAt":
+		return func(a *model.Token, b *model.Token) bool { return a.CreatedAt < b.CreatedAt }
+	default:
+		return func(a *model.Token, b *model.Token) bool { return a.Number < b.Number }
+	}
+}
+
+func resolveTokens(p graphql.ResolveParams) (interface{}, error) {
+	offset, limit, err := connectionArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	all, _ := core.QueryTokens(0, -1)
+	filtered := all[:0]
+	for _, token := range all {
+		if tick, ok := p.Args["tick"].(string); ok && !strings.Contains(model.NormalizeTick(token.Tick), model.NormalizeTick(tick)) {
+			continue
+		}
+		if completed, ok := p.Args["completed"].(bool); ok && completed != (token.Minted.Cmp(token.Max) >= 0) {
+			continue
+		}
+		filtered = append(filtered, token)
+	}
+
+	field, _ := p.Args["orderBy"].(string)
+	desc := p.Args["orderDirection"] == "desc"
+	less := tokenLess(field)
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if desc {
+			return less(filtered[j], filtered[i])
+		}
+		return less(filtered[i], filtered[j])
+	})
+
+	return newConnection(pageOf(filtered, offset, limit), offset, len(filtered)), nil
+}
+
+func newGraphQLSchema() (graphql.Schema, error) {
+	var tokenType, accountType *graphql.Object
+
+	balanceType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Balance",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"tick":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"amount": &graphql.Field{Type: decimalScalar},
+				"token": &graphql.Field{
+					Type: tokenType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveToken(p.Source.(*core.Balance).Tick)
+					},
+				},
+			}
+		}),
+	})
+
+	accountType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Account",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"balances": &graphql.Field{
+				Type: graphql.NewList(balanceType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return core.QueryBalances(p.Source.(*account).Address), nil
+				},
+			},
+		},
+	})
+
+	holderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Holder",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"balance": &graphql.Field{Type: decimalScalar},
+			"account": &graphql.Field{
+				Type: accountType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return &account{Address: p.Source.(*core.Holder).Address}, nil
+				},
+			},
+		},
+	})
+
+	inscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Inscription",
+		Fields: graphql.Fields{
+			"hash":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"number":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"from":        &graphql.Field{Type: graphql.String},
+			"to":          &graphql.Field{Type: graphql.String},
+			"block":       &graphql.Field{Type: graphql.Int},
+			"idx":         &graphql.Field{Type: graphql.Int},
+			"timestamp":   &graphql.Field{Type: graphql.Int},
+			"contentType": &graphql.Field{Type: graphql.String},
+			"content":     &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	rrc20Type := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RRC20",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"number":    &graphql.Field{Type: graphql.Int},
+				"hash":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"tick":      &graphql.Field{Type: graphql.String},
+				"operation": &graphql.Field{Type: graphql.String},
+				"from":      &graphql.Field{Type: graphql.String},
+				"to":        &graphql.Field{Type: graphql.String},
+				"precision": &graphql.Field{Type: graphql.Int},
+				"max":       &graphql.Field{Type: decimalScalar},
+				"limit":     &graphql.Field{Type: decimalScalar},
+				"timestamp": &graphql.Field{Type: graphql.Int},
+				"amount":    &graphql.Field{Type: decimalScalar},
+				"valid": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return int(p.Source.(*model.RRC20).Valid), nil
+					},
+				},
+				"validMessage": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*model.RRC20).Valid.String(), nil
+					},
+				},
+				"token": &graphql.Field{
+					Type: tokenType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveToken(p.Source.(*model.RRC20).Tick)
+					},
+				},
+				"inscription": &graphql.Field{
+					Type: inscriptionType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if inscription, ok := core.QueryInscriptionByHash(p.Source.(*model.RRC20).Hash); ok {
+							return inscription, nil
+						}
+						return nil, nil
+					},
+				},
+			}
+		}),
+	})
+	recordConnection := connectionType("RRC20", rrc20Type)
+
+	listingType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ListedRecord",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"hash":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"tick":       &graphql.Field{Type: graphql.String},
+				"originAddr": &graphql.Field{Type: graphql.String},
+				"listedTo":   &graphql.Field{Type: graphql.String},
+				"amount":     &graphql.Field{Type: decimalScalar},
+				"listedTs":   &graphql.Field{Type: graphql.Int},
+				"token": &graphql.Field{
+					Type: tokenType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveToken(p.Source.(*model.ListedRecord).Tick)
+					},
+				},
+			}
+		}),
+	})
+	listingConnection := connectionType("ListedRecord", listingType)
+
+	tokenType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Token",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"tick":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"number":         &graphql.Field{Type: graphql.Int},
+				"precision":      &graphql.Field{Type: graphql.Int},
+				"max":            &graphql.Field{Type: decimalScalar},
+				"limit":          &graphql.Field{Type: decimalScalar},
+				"minted":         &graphql.Field{Type: decimalScalar},
+				"progress":       &graphql.Field{Type: graphql.Int},
+				"trxs":           &graphql.Field{Type: graphql.Int},
+				"createdAt":      &graphql.Field{Type: graphql.Int},
+				"completedAt":    &graphql.Field{Type: graphql.Int},
+				"deployAddress":  &graphql.Field{Type: graphql.String},
+				"deployHash":     &graphql.Field{Type: graphql.String},
+				"confusable":     &graphql.Field{Type: graphql.Boolean},
+				"confusableWith": &graphql.Field{Type: graphql.String},
+				"holderCount": &graphql.Field{
+					Type: graphql.Int,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*model.Token).Holders, nil
+					},
+				},
+				"holders": &graphql.Field{
+					Type: connectionType("Holder", holderType),
+					Args: withConnectionArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						offset, limit, err := connectionArgs(p.Args)
+						if err != nil {
+							return nil, err
+						}
+						holders, total, _ := core.QueryHolders(p.Source.(*model.Token).Tick, offset, limit)
+						return newConnection(holders, offset, total), nil
+					},
+				},
+				"operations": &graphql.Field{
+					Type: recordConnection,
+					Args: withConnectionArgs(graphql.FieldConfigArgument{
+						"address": &graphql.ArgumentConfig{Type: graphql.String},
+						"op":      &graphql.ArgumentConfig{Type: graphql.String},
+						"valid":   &graphql.ArgumentConfig{Type: graphql.Int},
+					}),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveRecords(core.RecordFilter{Tick: p.Source.(*model.Token).Tick}, p.Args)
+					},
+				},
+				"listings": &graphql.Field{
+					Type: listingConnection,
+					Args: withConnectionArgs(graphql.FieldConfigArgument{
+						"address": &graphql.ArgumentConfig{Type: graphql.String},
+					}),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return resolveListings(core.ListingFilter{Tick: p.Source.(*model.Token).Tick}, p.Args)
+					},
+				},
+			}
+		}),
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"tokens": &graphql.Field{
+				Type: connectionType("Token", tokenType),
+				Args: withConnectionArgs(graphql.FieldConfigArgument{
+					"tick":           &graphql.ArgumentConfig{Type: graphql.String, Description: "ticks containing this"},
+					"completed":      &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"orderBy":        &graphql.ArgumentConfig{Type: tokenOrderField, DefaultValue: "number"},
+					"orderDirection": &graphql.ArgumentConfig{Type: orderDirection, DefaultValue: "asc"},
+				}),
+				Resolve: resolveTokens,
+			},
+			"token": &graphql.Field{
+				Type: tokenType,
+				Args: graphql.FieldConfigArgument{
+					"tick": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveToken(p.Args["tick"].(string))
+				},
+			},
+			"account": &graphql.Field{
+				Type: accountType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return &account{Address: strings.ToLower(p.Args["address"].(string))}, nil
+				},
+			},
+			"operations": &graphql.Field{
+				Type: recordConnection,
+				Args: withConnectionArgs(recordFilterArgs()),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveRecords(core.RecordFilter{}, p.Args)
+				},
+			},
+			"inscription": &graphql.Field{
+				Type: inscriptionType,
+				Args: graphql.FieldConfigArgument{
+					"hash":   &graphql.ArgumentConfig{Type: graphql.String},
+					"number": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var inscription *model.Inscription
+					var ok bool
+					if hash, isHash := p.Args["hash"].(string); isHash {
+						inscription, ok = core.QueryInscriptionByHash(hash)
+					} else if number, isNumber := p.Args["number"].(int); isNumber && number >= 0 {
+						inscription, ok = core.QueryInscriptionByNumber(uint64(number))
+					}
+					if !ok {
+						return nil, nil
+					}
+					return inscription, nil
+				},
+			},
+			"listings": &graphql.Field{
+				Type: listingConnection,
+				Args: withConnectionArgs(graphql.FieldConfigArgument{
+					"tick":    &graphql.ArgumentConfig{Type: graphql.String},
+					"address": &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveListings(core.ListingFilter{}, p.Args)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func (s *Server) handleGraphQL(schema graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		switch r.Method {
+		case http.MethodGet:
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if variables := r.URL.Query().Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					writeError(w, http.StatusBadRequest, errInvalidParam("variables").Error())
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid graphql request")
+				return
+			}
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if err := checkQueryCost(schema, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}})
+			return
+		}
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        r.Context(),
+		})
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"strconv"
+	"strings"
+)
+
+// REST routes, all responses are json:
+//
+//	GET /api/v1/tokens                          token list in deploy order
+//	GET /api/v1/tokens/{tick}                   token detail
+//	GET /api/v1/tokens/{tick}/holders?block=                    holders sorted by balance
+//	GET /api/v1/addresses/{address}/balances?block=             all balances of an address
+//	GET /api/v1/addresses/{address}/balances/{tick}?block=      balance of one tick
+//	GET /api/v1/addresses/{address}/balances/{tick}/history     balance changes in block order
+//	GET /api/v1/addresses/{address}/balances/{tick}/proof?block= merkle proof of the balance
+//	GET /api/v1/rrc20/ops?tick=&address=&op=&valid=
+//	GET /api/v1/inscriptions/{hash or number}
+//	GET /api/v1/listings?tick=&address=         active listings
+//	GET /api/v1/blocks/{number or latest}/root  state root after the block
+//
+// List endpoints are paginated with the page and limit query parameters.
+// With block set, balances and holders are the ones at the end of that block.
+func (s *Server) registerRestRoutes() {
+	s.mux.HandleFunc("/api/v1/tokens", getOnly(s.handleTokens))
+	s.mux.HandleFunc("/api/v1/tokens/", getOnly(s.handleToken))
+	s.mux.HandleFunc("/api/v1/addresses/", getOnly(s.handleAddress))
+	s.mux.HandleFunc("/api/v1/rrc20/ops", getOnly(s.handleRecords))
+	s.mux.HandleFunc("/api/v1/inscriptions/", getOnly(s.handleInscription))
+	s.mux.HandleFunc("/api/v1/listings", getOnly(s.handleListings))
+	s.mux.HandleFunc("/api/v1/blocks/", getOnly(s.handleBlock))
+}
+
+func getOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// pathParams splits the path after prefix into its segments
+func pathParams(r *http.Request, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
+
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	page, limit, offset, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, total := core.QueryTokens(offset, limit)
+	writePage(w, page, limit, total, tokens)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	params := pathParams(r, "/api/v1/tokens/")
+	switch {
+	case len(params) == 1:
+		token, ok := core.QueryToken(params[0])
+		if !ok {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		writeData(w, token)
+	case len(params) == 2 && params[1] == "holders":
+		page, limit, offset, err := parsePage(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		block, historical, err := parseBlock(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var holders []*core.Holder
+		var total int
+		var ok bool
+		if historical {
+			holders, total, ok, err = core.QueryHoldersAt(params[0], block, offset, limit)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		} else {
+			holders, total, ok = core.QueryHolders(params[0], offset, limit)
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		writePage(w, page, limit, total, holders)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	params := pathParams(r, "/api/v1/addresses/")
+	if len(params) < 2 || params[1] != "balances" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(params) == 2:
+		block, historical, err := parseBlock(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !historical {
+			writeData(w, core.QueryBalances(params[0]))
+			return
+		}
+		balances, err := core.QueryBalancesAt(params[0], block)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeData(w, balances)
+	case len(params) == 3:
+		block, historical, err := parseBlock(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !historical {
+			block = core.QueryLatestBlockNumber()
+		}
+		amount, err := core.QueryBalanceAt(params[0], params[2], block)
+		if errors.Is(err, core.ErrorTokenNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		token, _ := core.QueryToken(params[2])
+		writeData(w, &core.Balance{Tick: token.Tick, Amount: amount})
+	case len(params) == 4 && params[3] == "history":
+		page, limit, offset, err := parsePage(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		changes, total, ok := core.QueryBalanceHistory(params[0], params[2], offset, limit)
+		if !ok {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		writePage(w, page, limit, total, changes)
+	case len(params) == 4 && params[3] == "proof":
+		block, historical, err := parseBlock(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !historical {
+			block = core.QueryLatestBlockNumber()
+		}
+		proof, err := core.QueryBalanceProof(params[0], params[2], block)
+		switch {
+		case errors.Is(err, core.ErrorTokenNotFound), errors.Is(err, core.ErrorBalanceNotFound), errors.Is(err, core.ErrorStateRootNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeData(w, proof)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+	page, limit, offset, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := core.RecordFilter{
+		Tick:      query.Get("tick"),
+		Address:   query.Get("address"),
+		Operation: model.RRC20Operation(query.Get("op")),
+	}
+	if value := query.Get("valid"); value != "" {
+		code, err := strconv.ParseInt(value, 10, 8)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidParam("valid").Error())
+			return
+		}
+		valid := model.ValideCode(code)
+		filter.Valid = &valid
+	}
+
+	records, total := core.QueryRecords(filter, offset, limit)
+	writePage(w, page, limit, total, records)
+}
+
+func (s *Server) handleInscription(w http.ResponseWriter, r *http.Request) {
+	params := pathParams(r, "/api/v1/inscriptions/")
+	if len(params) != 1 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var inscription *model.Inscription
+	var ok bool
+	if strings.HasPrefix(params[0], "0x") {
+		inscription, ok = core.QueryInscriptionByHash(params[0])
+	} else if number, err := strconv.ParseUint(params[0], 10, 64); err == nil {
+		inscription, ok = core.QueryInscriptionByNumber(number)
+	} else {
+		writeError(w, http.StatusBadRequest, "expect a transaction hash or an inscription number")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "inscription not found")
+		return
+	}
+	writeData(w, inscription)
+}
+
+func (s *Server) handleListings(w http.ResponseWriter, r *http.Request) {
+	page, limit, offset, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := core.ListingFilter{
+		Tick:    r.URL.Query().Get("tick"),
+		Address: r.URL.Query().Get("address"),
+	}
+	listings, total := core.QueryListings(filter, offset, limit)
+	writePage(w, page, limit, total, listings)
+}
+
+func (s *Server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	params := pathParams(r, "/api/v1/blocks/")
+	if len(params) != 2 || params[1] != "root" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	block, err := parseBlockNumber(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	root, ok, err := core.QueryBlockRoot(block)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "state root not found")
+		return
+	}
+	writeData(w, root)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/utils/generics/must"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type Server struct {
+	addr string
+	mux  *http.ServeMux
+}
+
+func NewServer(addr string) *Server {
+	s := &Server{
+		addr: addr,
+		mux:  http.NewServeMux(),
+	}
+	s.registerRestRoutes()
+	s.mux.HandleFunc("/api/v1/graphql", s.handleGraphQL(must.Must(newGraphQLSchema())))
+	return s
+}
+
+// Handle registers an extra handler, e.g. for endpoints served by other packages
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ListenAndServe() error {
+	logrus.Infof("api server listening on %s", s.addr)
+	return http.ListenAndServe(s.addr, s.mux)
+}
+
+type pageResponse struct {
+	Total int         `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Data  interface{} `json:"data"`
+}
+
+type dataResponse struct {
+	Data interface{} `json:"data"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("write response err: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &errorResponse{Error: msg})
+}
+
+func writeData(w http.ResponseWriter, v interface{}) {
+	writeJSON(w, http.StatusOK, &dataResponse{Data: v})
+}
+
+func writePage(w http.ResponseWriter, page int, limit int, total int, v interface{}) {
+	writeJSON(w, http.StatusOK, &pageResponse{Total: total, Page: page, Limit: limit, Data: v})
+}
+
+// parsePage reads the 1-based page and limit query parameters and returns them with the offset
+func parsePage(r *http.Request) (int, int, int, error) {
+	page, limit := 1, defaultPageLimit
+	if value := r.URL.Query().Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, 0, 0, errInvalidParam("page")
+		}
+		page = n
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, 0, 0, errInvalidParam("limit")
+		}
+		limit = n
+	}
+	return page, limit, (page - 1) * limit, nil
+}
+
+// parseBlock reads the optional block query parameter, ok is false when it is absent
+func parseBlock(r *http.Request) (uint64, bool, error) {
+	value := r.URL.Query().Get("block")
+	if value == "" {
+		return 0, false, nil
+	}
+	block, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, errInvalidParam("block")
+	}
+	return block, true, nil
+}
+
+// parseBlockNumber parses a block path segment, latest is the last indexed block
+func parseBlockNumber(value string) (uint64, error) {
+	if value == "latest" {
+		return core.QueryLatestBlockNumber(), nil
+	}
+	block, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errInvalidParam("block")
+	}
+	return block, nil
+}
+
+type errInvalidParam string
+
+func (e errInvalidParam) Error() string {
+	return "invalid parameter " + string(e)
+}
@@ -0,0 +1,204 @@
+// Package attest signs the state roots of the indexer with an operator key, so consumers
+// holding the operator address can detect tampered or divergent indexers.
+package attest
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+// cacheSize is the number of recent attestations kept, older ones are signed again on request
+const cacheSize = 1024
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrWrongSigner      = errors.New("signed by another address")
+	ErrNotAttested      = errors.New("block is not attested")
+	ErrNotComputed      = errors.New("state root was imported from a snapshot")
+)
+
+// Attestation is the operator's signature of the state root after a block
+type Attestation struct {
+	ChainID     uint64
+	BlockNumber uint64
+	BlockHash   string
+	StateRoot   merkle.Hash
+	Signer      string
+	// Signature is the 65 bytes r || s || v with v 27 or 28, over Digest
+	Signature string
+}
+
+// Digest is the EIP-191 personal message hash of
+// keccak256(uint256 chainId || uint256 blockNumber || bytes32 blockHash || bytes32 stateRoot),
+// so the signature can also be checked with ecrecover in a contract
+func (a *Attestation) Digest() []byte {
+	var msg [128]byte
+	binary.BigEndian.PutUint64(msg[24:32], a.ChainID)
+	binary.BigEndian.PutUint64(msg[56:64], a.BlockNumber)
+	blockHash := common.HexToHash(a.BlockHash)
+	copy(msg[64:96], blockHash[:])
+	copy(msg[96:128], a.StateRoot[:])
+	return accounts.TextHash(crypto.Keccak256(msg[:]))
+}
+
+// Verify checks that the attestation is signed by operator, the Signer field is not trusted
+func (a *Attestation) Verify(operator string) error {
+	sig, err := hexutil.Decode(a.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return ErrInvalidSignature
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(a.Digest(), sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if crypto.PubkeyToAddress(*pub) != common.HexToAddress(operator) {
+		return ErrWrongSigner
+	}
+	return nil
+}
+
+// Signer signs the state root of every Interval-th block
+type Signer struct {
+	ChainID  uint64
+	Interval uint64
+	key      *ecdsa.PrivateKey
+	address  common.Address
+}
+
+// NewSigner loads the hex private key in keyFile
+func NewSigner(keyFile string, chainID uint64, interval uint64) (*Signer, error) {
+	if interval == 0 {
+		return nil, fmt.Errorf("invalid attestation interval 0")
+	}
+	key, err := crypto.LoadECDSA(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load attestation key: %w", err)
+	}
+	return &Signer{
+		ChainID:  chainID,
+		Interval: interval,
+		key:      key,
+		address:  crypto.PubkeyToAddress(key.PublicKey),
+	}, nil
+}
+
+func (s *Signer) Address() string {
+	return s.address.Hex()
+}
+
+// Attests reports whether block is one of the attested ones
+func (s *Signer) Attests(block uint64) bool {
+	return block%s.Interval == 0
+}
+
+func (s *Signer) Sign(root *model.BlockRoot) (*Attestation, error) {
+	a := &Attestation{
+		ChainID:     s.ChainID,
+		BlockNumber: root.Number,
+		BlockHash:   root.Hash,
+		StateRoot:   root.StateRoot,
+		Signer:      s.Address(),
+	}
+	sig, err := crypto.Sign(a.Digest(), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	a.Signature = hexutil.Encode(sig)
+	return a, nil
+}
+
+// Attester signs the attested blocks as they are committed and keeps the recent attestations
+type Attester struct {
+	signer *Signer
+
+	lock   sync.RWMutex
+	recent map[uint64]*Attestation
+	order  []uint64
+}
+
+func NewAttester(signer *Signer) *Attester {
+	a := &Attester{
+		signer: signer,
+		recent: make(map[uint64]*Attestation),
+	}
+	core.OnBlockCommitted(a.handleBlock)
+	return a
+}
+
+func (a *Attester) Signer() *Signer {
+	return a.signer
+}
+
+func (a *Attester) handleBlock(result *core.BlockResult) {
+	if result.Root == nil || !a.signer.Attests(result.Number) {
+		return
+	}
+	attestation, err := a.signer.Sign(result.Root)
+	if err != nil {
+		logrus.Errorf("sign attestation of block %d err: %v", result.Number, err)
+		return
+	}
+	a.add(attestation)
+}
+
+func (a *Attester) add(attestation *Attestation) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.recent[attestation.BlockNumber]; !ok {
+		a.order = append(a.order, attestation.BlockNumber)
+		if len(a.order) > cacheSize {
+			delete(a.recent, a.order[0])
+			a.order = a.order[1:]
+		}
+	}
+	a.recent[attestation.BlockNumber] = attestation
+}
+
+// Get returns the attestation of block, signing it if it is not a recent one. The roots imported
+// from a snapshot are not signed, they are only as good as the snapshot's writer.
+func (a *Attester) Get(block uint64) (*Attestation, error) {
+	if !a.signer.Attests(block) {
+		return nil, ErrNotAttested
+	}
+	a.lock.RLock()
+	attestation, ok := a.recent[block]
+	a.lock.RUnlock()
+	if ok {
+		return attestation, nil
+	}
+
+	if block <= core.QueryImportedTo() {
+		return nil, ErrNotComputed
+	}
+	root, ok, err := core.QueryBlockRoot(block)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, core.ErrorStateRootNotFound
+	}
+	return a.signer.Sign(root)
+}
+
+// Latest returns the attestation of the last attested block indexed
+func (a *Attester) Latest() (*Attestation, error) {
+	block := core.QueryLatestBlockNumber()
+	return a.Get(block - block%a.signer.Interval)
+}
@@ -0,0 +1,142 @@
+package attest
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newSigner(t *testing.T, interval uint64) (*Signer, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	if err := crypto.SaveECDSA(path, key); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(path, 1, interval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, key
+}
+
+func TestAttestationVerify(t *testing.T) {
+	signer, _ := newSigner(t, 10)
+	other, _ := newSigner(t, 10)
+	root := &model.BlockRoot{Number: 100, Hash: "0x" + strings.Repeat("ab", 32), StateRoot: merkle.Hash{1}}
+	attestation, err := signer.Sign(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := func(fn func(a *Attestation)) *Attestation {
+		copied := *attestation
+		fn(&copied)
+		return &copied
+	}
+	tests := []struct {
+		name        string
+		attestation *Attestation
+		operator    string
+		err         error
+	}{
+		{"signer", attestation, signer.Address(), nil},
+		{"lowercase operator", attestation, strings.ToLower(signer.Address()), nil},
+		{"other operator", attestation, other.Address(), ErrWrongSigner},
+		{"claimed signer is not trusted", tampered(func(a *Attestation) { a.Signer = other.Address() }), other.Address(), ErrWrongSigner},
+		{"other root", tampered(func(a *Attestation) { a.StateRoot = merkle.Hash{2} }), signer.Address(), ErrWrongSigner},
+		{"other block", tampered(func(a *Attestation) { a.BlockNumber++ }), signer.Address(), ErrWrongSigner},
+		{"other chain", tampered(func(a *Attestation) { a.ChainID = 2 }), signer.Address(), ErrWrongSigner},
+		{"not hex", tampered(func(a *Attestation) { a.Signature = "zz" }), signer.Address(), ErrInvalidSignature},
+		{"short", tampered(func(a *Attestation) { a.Signature = a.Signature[:64] }), signer.Address(), ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.attestation.Verify(tt.operator); !errors.Is(err, tt.err) {
+				t.Fatalf("Verify = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSignatureRecoversWithEcrecover(t *testing.T) {
+	signer, key := newSigner(t, 1)
+	attestation, err := signer.Sign(&model.BlockRoot{Number: 7, Hash: "0x01", StateRoot: merkle.Hash{3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := hexutil.MustDecode(attestation.Signature)
+	if v := sig[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+		t.Fatalf("v = %d, want 27 or 28", v)
+	}
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(attestation.Digest(), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("recovered another key")
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSigner(path, 1, 1); err == nil {
+		t.Error("NewSigner with an invalid key succeeded")
+	}
+	signer, _ := newSigner(t, 5)
+	for block, attests := range map[uint64]bool{0: true, 5: true, 7: false, 10: true} {
+		if got := signer.Attests(block); got != attests {
+			t.Errorf("Attests(%d) = %v, want %v", block, got, attests)
+		}
+	}
+}
+
+func TestAttesterGet(t *testing.T) {
+	defer core.ImportState(&core.State{})
+	core.ImportState(&core.State{Version: core.StateVersion, LatestBlockNumber: 120, ImportedTo: 100})
+
+	signer, _ := newSigner(t, 10)
+	a := &Attester{signer: signer, recent: make(map[uint64]*Attestation)}
+	a.handleBlock(&core.BlockResult{Number: 120, Root: &model.BlockRoot{Number: 120, StateRoot: merkle.Hash{1}}})
+	a.handleBlock(&core.BlockResult{Number: 121, Root: &model.BlockRoot{Number: 121, StateRoot: merkle.Hash{2}}})
+
+	tests := []struct {
+		block uint64
+		err   error
+	}{
+		{120, nil},
+		{121, ErrNotAttested},
+		{110, core.ErrorStateRootNotFound},
+		{100, ErrNotComputed},
+		{90, ErrNotComputed},
+	}
+	for _, tt := range tests {
+		attestation, err := a.Get(tt.block)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Get(%d) err = %v, want %v", tt.block, err, tt.err)
+			continue
+		}
+		if err == nil && (attestation.BlockNumber != tt.block || attestation.Verify(signer.Address()) != nil) {
+			t.Errorf("Get(%d) = %+v", tt.block, attestation)
+		}
+	}
+	if latest, err := a.Latest(); err != nil || latest.BlockNumber != 120 {
+		t.Fatalf("Latest = %+v, %v", latest, err)
+	}
+}
@@ -0,0 +1,40 @@
+package attest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// Fetch gets the attestation of block from the api of the instance at base. It is not verified,
+// the caller checks it against the operator address it trusts.
+func Fetch(base string, block uint64) (*Attestation, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Get(fmt.Sprintf("%s%s%d", strings.TrimSuffix(base, "/"), routePrefix, block))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotAttested
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("attestation: %s", resp.Status)
+	}
+	var res struct {
+		Data *Attestation `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("attestation: %w", err)
+	}
+	if res.Data == nil {
+		return nil, errors.New("attestation: empty")
+	}
+	return res.Data, nil
+}
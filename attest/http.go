@@ -0,0 +1,89 @@
+package attest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rose-scriptions-open-indexer/core"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler serves the attestations:
+//
+//	GET /api/v1/attestations/operator    the signer address, chain id and interval
+//	GET /api/v1/attestations/latest      attestation of the last attested block
+//	GET /api/v1/attestations/{number}    attestation of a block, a multiple of the interval
+type Handler struct {
+	attester *Attester
+}
+
+const routePrefix = "/api/v1/attestations/"
+
+func NewHandler(attester *Attester) *Handler {
+	return &Handler{attester: attester}
+}
+
+// Register adds the routes to mux-like registrars such as api.Server
+func (h *Handler) Register(handle func(pattern string, handler http.Handler)) {
+	handle(routePrefix, h)
+}
+
+type operator struct {
+	Address  string
+	ChainID  uint64
+	Interval uint64
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	param := strings.Trim(strings.TrimPrefix(r.URL.Path, routePrefix), "/")
+
+	var attestation *Attestation
+	var err error
+	switch param {
+	case "operator":
+		signer := h.attester.Signer()
+		writeData(w, &operator{Address: signer.Address(), ChainID: signer.ChainID, Interval: signer.Interval})
+		return
+	case "latest":
+		attestation, err = h.attester.Latest()
+	default:
+		block, parseErr := strconv.ParseUint(param, 10, 64)
+		if parseErr != nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		attestation, err = h.attester.Get(block)
+	}
+
+	switch {
+	case errors.Is(err, ErrNotAttested), errors.Is(err, ErrNotComputed), errors.Is(err, core.ErrorStateRootNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeData(w, attestation)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("write response err: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeData(w http.ResponseWriter, v interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": v})
+}
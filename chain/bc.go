@@ -5,7 +5,10 @@ import (
 	"encoding/hex"
 	"math/big"
 	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/metrics"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -25,7 +28,9 @@ func NewBlockchainClient(ethURL string) (*BlockchainClient, error) {
 }
 
 func (bc *BlockchainClient) GetBlock(blockNumber int64) (*types.Block, error) {
+	start := time.Now()
 	block, err := bc.client.BlockByNumber(context.Background(), big.NewInt(blockNumber))
+	metrics.ObserveRPC("BlockByNumber", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +38,9 @@ func (bc *BlockchainClient) GetBlock(blockNumber int64) (*types.Block, error) {
 }
 
 func (bc *BlockchainClient) GetBlockReceiptsByAPI(blockNumber int64) ([]*types.Receipt, error) {
+	start := time.Now()
 	block, err := bc.client.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNumber)))
+	metrics.ObserveRPC("BlockReceipts", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +50,10 @@ func (bc *BlockchainClient) GetBlockReceiptsByAPI(blockNumber int64) ([]*types.R
 func (bc *BlockchainClient) GetBlockReceipts(block *types.Block) ([]*types.Receipt, error) {
 	var res []*types.Receipt
 	for _, tx := range block.Transactions() {
-		if receipt, err := bc.client.TransactionReceipt(context.Background(), tx.Hash()); err != nil {
+		start := time.Now()
+		receipt, err := bc.client.TransactionReceipt(context.Background(), tx.Hash())
+		metrics.ObserveRPC("TransactionReceipt", start, err)
+		if err != nil {
 			logrus.Errorf("GetBlockReceipts %v err: %v", tx.Hash(), err)
 			return nil, err
 		} else {
@@ -54,8 +64,53 @@ func (bc *BlockchainClient) GetBlockReceipts(block *types.Block) ([]*types.Recei
 	return res, nil
 }
 
+func (bc *BlockchainClient) GetChainID() (uint64, error) {
+	start := time.Now()
+	chainID, err := bc.client.ChainID(context.Background())
+	metrics.ObserveRPC("ChainID", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return chainID.Uint64(), nil
+}
+
+// GetTransaction returns a mined transaction in the indexer's form
+func (bc *BlockchainClient) GetTransaction(hash string) (*model.ChainTransaction, error) {
+	start := time.Now()
+	tx, _, err := bc.client.TransactionByHash(context.Background(), common.HexToHash(hash))
+	metrics.ObserveRPC("TransactionByHash", start, err)
+	if err != nil {
+		return nil, err
+	}
+	start = time.Now()
+	receipt, err := bc.client.TransactionReceipt(context.Background(), tx.Hash())
+	metrics.ObserveRPC("TransactionReceipt", start, err)
+	if err != nil {
+		return nil, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var to string
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	return &model.ChainTransaction{
+		Id:    tx.Hash().Hex(),
+		From:  from.Hex(),
+		To:    to,
+		Block: receipt.BlockNumber.Uint64(),
+		Idx:   uint32(receipt.TransactionIndex),
+		Input: "0x" + hex.EncodeToString(tx.Data()),
+	}, nil
+}
+
 func (bc *BlockchainClient) GetLatestBlockNumber() (int64, error) {
+	start := time.Now()
 	header, err := bc.client.HeaderByNumber(context.Background(), nil)
+	metrics.ObserveRPC("HeaderByNumber", start, err)
 	if err != nil {
 		return 0, err
 	}
@@ -65,12 +120,13 @@ func (bc *BlockchainClient) GetLatestBlockNumber() (int64, error) {
 func ConvertBlockToChainBlock(block *types.Block, receipts []*types.Receipt) *model.ChainBlock {
 	chainBlock := &model.ChainBlock{
 		Number:    block.Number().Uint64(),
+		Hash:      block.Hash().Hex(),
 		Timestamp: block.Time(),
 	}
 	for idx, tx := range block.Transactions() {
 		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
 		if err != nil {
-			logrus.Fatal("Failed to get sender: %v", err)
+			logrus.Fatalf("Failed to get sender: %v", err)
 			continue
 		}
 
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+type buildResult struct {
+	DataURI  string
+	Calldata string `json:",omitempty"`
+	Valid    model.ValideCode
+	Message  string
+	// DryRun is the validation against the latest checkpoint, with -from and -to
+	DryRun *core.DryRunResult `json:",omitempty"`
+}
+
+// runBuild prints the calldata of an rrc-20 operation, validated statically and
+// with -from and -to also against the latest checkpoint
+func runBuild(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	op := flags.String("op", "", "deploy, mint, transfer or list, required")
+	tick := flags.String("tick", "", "tick, required")
+	max := flags.String("max", "", "deploy max supply, its decimals are the token precision")
+	lim := flags.String("lim", "", "deploy mint limit")
+	amt := flags.String("amt", "", "mint, transfer or list amount")
+	from := flags.String("from", "", "sender, validates against the saved state together with -to")
+	to := flags.String("to", "", "transaction recipient: the receiver of a transfer, the market of a list")
+	flags.Parse(args)
+
+	if *op == "" || *tick == "" {
+		fmt.Fprintln(os.Stderr, "build: -op and -tick are required")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	call := &model.RRC20Call{
+		Operation: model.RRC20Operation(*op),
+		Tick:      *tick,
+		Max:       *max,
+		Limit:     *lim,
+		Amount:    *amt,
+	}
+	res := &buildResult{DataURI: call.DataURI()}
+	res.Calldata, res.Valid = call.Calldata()
+	if res.Valid == model.ValidCodeOK && *from != "" && *to != "" {
+		app := newApp()
+		app.restore(math.MaxUint64)
+		res.DryRun, res.Valid = core.DryRunCall(call, *from, *to)
+	}
+	res.Message = res.Valid.String()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(res); err != nil {
+		logrus.Fatalf("encode err: %v", err)
+	}
+	if res.Valid != model.ValidCodeOK {
+		os.Exit(1)
+	}
+}
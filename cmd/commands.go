@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"rose-scriptions-open-indexer/core"
+	"runtime"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runBackfill indexes [from, to] from the checkpoint below from, the checkpoints above it are rebuilt.
+// Without -from it continues from the latest checkpoint.
+func runBackfill(args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := flags.Uint64("from", 0, "first block to index, the block after the latest checkpoint by default")
+	to := flags.Uint64("to", 0, "last block to index, required")
+	flags.Parse(args)
+
+	if *to == 0 {
+		fmt.Fprintln(os.Stderr, "backfill: -to is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+	app := newApp()
+
+	if *from == 0 {
+		if latest := app.latestCheckpoint(); latest > *to {
+			logrus.Fatalf("checkpoint %d is after -to %d, use -from to rebuild", latest, *to)
+		}
+		app.restore(*to)
+	} else {
+		if *from > *to {
+			logrus.Fatalf("-from %d is after -to %d", *from, *to)
+		}
+		app.restore(*from - 1)
+		if err := app.store.Truncate(*from - 1); err != nil {
+			logrus.Fatalf("Failed to truncate checkpoints: %v", err)
+		}
+	}
+
+	logrus.Infof("backfill from %d to %d", core.LatestBlockNumber+1, *to)
+	app.syncTo(*to)
+	app.checkpoint(true)
+}
+
+// runRewind rolls the saved state back to a block: the checkpoint below it is replayed up to it
+// and saved, the later checkpoints are dropped so the next run continues from there.
+func runRewind(args []string) {
+	flags := flag.NewFlagSet("rewind", flag.ExitOnError)
+	to := flags.Uint64("to", 0, "block to rewind to, required")
+	flags.Parse(args)
+
+	if *to == 0 {
+		fmt.Fprintln(os.Stderr, "rewind: -to is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+	app := newApp()
+	if latest := app.latestCheckpoint(); *to > latest {
+		logrus.Fatalf("cannot rewind to %d, the latest checkpoint is %d", *to, latest)
+	}
+
+	// the later checkpoints are dropped before the replay saves any, stores without history refuse to
+	// replace a newer state
+	app.restore(*to)
+	if err := app.store.Truncate(*to); err != nil {
+		logrus.Fatalf("Failed to truncate checkpoints: %v", err)
+	}
+	app.syncTo(*to)
+	app.checkpoint(true)
+	logrus.Infof("rewound to %d", *to)
+}
+
+// runInspect prints what the latest checkpoint indexed for a transaction or a block,
+// the state root of a block or the invariant violations
+func runInspect(args []string) {
+	var valid bool
+	switch {
+	case len(args) == 2:
+		valid = args[0] == "tx" || args[0] == "block" || args[0] == "root"
+	case len(args) == 1:
+		valid = args[0] == "root" || args[0] == "invariants"
+	}
+	if !valid {
+		fmt.Fprintln(os.Stderr, "usage: inspect tx <hash> | inspect block <n> | inspect root [n] | inspect invariants")
+		os.Exit(2)
+	}
+	app := newApp()
+	app.restore(math.MaxUint64)
+
+	var res interface{}
+	switch args[0] {
+	case "tx":
+		tx, ok := core.QueryTransaction(args[1])
+		if !ok {
+			logrus.Fatalf("transaction %s not indexed at %d", args[1], core.LatestBlockNumber)
+		}
+		res = tx
+	case "block":
+		number, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			logrus.Fatalf("invalid block %s", args[1])
+		}
+		if number > core.LatestBlockNumber {
+			logrus.Fatalf("block %d not indexed, latest is %d", number, core.LatestBlockNumber)
+		}
+		res = struct {
+			Number       uint64
+			Transactions []*core.Transaction
+		}{number, core.QueryBlockTransactions(number)}
+	case "root":
+		number := core.LatestBlockNumber
+		if len(args) == 2 {
+			var err error
+			if number, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				logrus.Fatalf("invalid block %s", args[1])
+			}
+		}
+		root, ok, err := core.QueryBlockRoot(number)
+		if err != nil {
+			logrus.Fatalf("block %d: %v", number, err)
+		}
+		if !ok {
+			logrus.Fatalf("no state root for block %d", number)
+		}
+		res = root
+	case "invariants":
+		violations := core.CheckInvariants(nil)
+		if violations == nil {
+			violations = make([]*core.InvariantViolation, 0)
+		}
+		res = violations
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(res); err != nil {
+		logrus.Fatalf("encode err: %v", err)
+	}
+}
+
+func runVersion(args []string) {
+	fmt.Printf("rose-scriptions-open-indexer %s %s\n", version, runtime.Version())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runDecode decodes calldata, a "data:" uri or a mined transaction and dry-runs it against the latest checkpoint
+func runDecode(args []string) {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	from := flags.String("from", "", "sender, required for calldata, overrides the one of a transaction")
+	to := flags.String("to", "", "recipient, required for calldata, overrides the one of a transaction")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: decode [-from address] [-to address] <0x calldata | data: uri | tx hash>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	input := flags.Arg(0)
+
+	app := newApp()
+	app.restore(math.MaxUint64)
+
+	var trx *model.ChainTransaction
+	if isTxHash(input) {
+		var err error
+		trx, err = app.client().GetTransaction(input)
+		if err != nil {
+			logrus.Fatalf("GetTransaction %s err: %v", input, err)
+		}
+	} else {
+		if strings.HasPrefix(input, "data:") {
+			input = "0x" + hex.EncodeToString([]byte(input))
+		}
+		trx = &model.ChainTransaction{Input: input}
+	}
+	if *from != "" {
+		trx.From = *from
+	}
+	if *to != "" {
+		trx.To = *to
+	}
+	if trx.From == "" || trx.To == "" {
+		fmt.Fprintln(os.Stderr, "decode: -from and -to are required for calldata")
+		os.Exit(2)
+	}
+	trx.Timestamp = uint64(time.Now().Unix())
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(core.DryRun(trx)); err != nil {
+		logrus.Fatalf("encode err: %v", err)
+	}
+}
+
+func isTxHash(s string) bool {
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"rose-scriptions-open-indexer/diverge"
+	"rose-scriptions-open-indexer/storage"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runDiverge finds the first block two sources disagree on and prints what they disagree about,
+// it exits with 1 if they diverge
+func runDiverge(args []string) {
+	flags := flag.NewFlagSet("diverge", flag.ExitOnError)
+	from := flags.Uint64("from", 0, "first block to compare, needed when both sources are apis")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: diverge [-from N] <source> <source>")
+		fmt.Fprintln(os.Stderr, "a source is local (the checkpoints in DATA_DIR), a checkpoint directory, a state dump (.json or .json.gz) or an api url")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	a, b := openSource(flags.Arg(0)), openSource(flags.Arg(1))
+
+	lo, hi, err := diverge.Range(a, b, *from)
+	if err != nil {
+		logrus.Fatalf("diverge: %v", err)
+	}
+	logrus.Infof("comparing %s and %s from %d to %d", a, b, lo, hi)
+	div, err := diverge.Find(a, b, lo, hi)
+	if err != nil {
+		logrus.Fatalf("diverge: %v", err)
+	}
+	if div == nil {
+		fmt.Printf("no divergence from %d to %d, state root %s\n", lo, hi, mustRoot(a, hi))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if div.AtStart {
+		fmt.Fprintf(w, "state roots already differ at the first common block %d\n", div.Block)
+	} else {
+		fmt.Fprintf(w, "first divergent block %d\n", div.Block)
+	}
+	fmt.Fprintf(w, "\tA %s\tB %s\n", a, b)
+	fmt.Fprintf(w, "Hash\t%s\t%s\n", div.A.Hash, div.B.Hash)
+	fmt.Fprintf(w, "StateRoot\t%s\t%s\n", div.A.StateRoot, div.B.StateRoot)
+	fmt.Fprintf(w, "TokensRoot\t%s\t%s\n", div.A.TokensRoot, div.B.TokensRoot)
+	fmt.Fprintf(w, "BalancesRoot\t%s\t%s\n", div.A.BalancesRoot, div.B.BalancesRoot)
+	fmt.Fprintf(w, "ListsRoot\t%s\t%s\n", div.A.ListsRoot, div.B.ListsRoot)
+	if div.A.Hash != div.B.Hash {
+		fmt.Fprintln(w, "the block hashes differ, the sources followed different chains")
+	}
+
+	if div.A.BalancesRoot != div.B.BalancesRoot {
+		balancesA, err := a.Balances(div.Block)
+		if err != nil {
+			logrus.Fatalf("%s balances: %v", a, err)
+		}
+		balancesB, err := b.Balances(div.Block)
+		if err != nil {
+			logrus.Fatalf("%s balances: %v", b, err)
+		}
+		fmt.Fprintf(w, "\nbalances at %d\n", div.Block)
+		fmt.Fprintln(w, "tick\taddress\tA\tB")
+		for _, diff := range diverge.DiffBalances(balancesA, balancesB) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", diff.Tick, diff.Address, diff.A, diff.B)
+		}
+	}
+
+	// tokens and listings are only kept for the last block, they are compared there
+	if div.A.TokensRoot != div.B.TokensRoot || div.A.ListsRoot != div.B.ListsRoot {
+		latestA, err := a.Latest()
+		if err != nil {
+			logrus.Fatalf("%s: %v", a, err)
+		}
+		latestB, err := b.Latest()
+		if err != nil {
+			logrus.Fatalf("%s: %v", b, err)
+		}
+		if div.A.TokensRoot != div.B.TokensRoot {
+			fmt.Fprintf(w, "\ntokens at %d and %d\n", latestA.Block, latestB.Block)
+			printFieldDiffs(w, "tick", diverge.DiffTokens(latestA.Tokens, latestB.Tokens))
+		}
+		if div.A.ListsRoot != div.B.ListsRoot {
+			fmt.Fprintf(w, "\nlistings at %d and %d\n", latestA.Block, latestB.Block)
+			printFieldDiffs(w, "hash", diverge.DiffLists(latestA.Lists, latestB.Lists))
+		}
+	}
+	w.Flush()
+	os.Exit(1)
+}
+
+func printFieldDiffs(w *tabwriter.Writer, key string, diffs []*diverge.FieldDiff) {
+	fmt.Fprintf(w, "%s\tfield\tA\tB\n", key)
+	for _, diff := range diffs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", diff.Key, diff.Field, diff.A, diff.B)
+	}
+}
+
+func mustRoot(source diverge.Source, block uint64) string {
+	root, err := source.Root(block)
+	if err != nil {
+		logrus.Fatalf("%s: %v", source, err)
+	}
+	return root.StateRoot.String()
+}
+
+func openSource(spec string) diverge.Source {
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		return diverge.NewHTTPSource(spec)
+	}
+	if spec == "local" {
+		app := newApp()
+		defer app.store.Close()
+		return loadSource(app.cfg.DataDir, app.store)
+	}
+	dir := spec
+	if info, err := os.Stat(dir); err != nil {
+		logrus.Fatalf("source %s: %v", spec, err)
+	} else if !info.IsDir() {
+		source, err := diverge.LoadStateFile(spec)
+		if err != nil {
+			logrus.Fatalf("source %s: %v", spec, err)
+		}
+		return source
+	}
+
+	store, err := storage.NewFileStore(dir, 0)
+	if err != nil {
+		logrus.Fatalf("source %s: %v", spec, err)
+	}
+	defer store.Close()
+	return loadSource(dir, store)
+}
+
+func loadSource(dir string, store storage.Store) diverge.Source {
+	state, err := store.Load()
+	if err != nil {
+		logrus.Fatalf("source %s: %v", dir, err)
+	}
+	return diverge.NewStateSource(fmt.Sprintf("%s@%d", dir, state.LatestBlockNumber), state)
+}
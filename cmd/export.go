@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"rose-scriptions-open-indexer/export"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runExport restores the checkpoint below -block, replays up to it and writes the holder snapshot with its checksum
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	block := flags.Uint64("block", 0, "height of the snapshot, required")
+	ticks := flags.String("tick", "", "comma separated ticks, all tokens if empty")
+	format := flags.String("format", export.FormatCSV, "output format, csv or json")
+	out := flags.String("out", "", "output file, snapshot-<block>.<format> by default")
+	flags.Parse(args)
+
+	if *block == 0 {
+		fmt.Fprintln(os.Stderr, "export: -block is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *format != export.FormatCSV && *format != export.FormatJSON {
+		fmt.Fprintf(os.Stderr, "export: unknown format %s\n", *format)
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("snapshot-%d.%s", *block, *format)
+	}
+
+	// the replay must not touch the saved state, the leveldb store only holds the latest one
+	app := newApp()
+	app.readOnly = true
+	app.restore(*block)
+	app.syncTo(*block)
+
+	var tickList []string
+	if *ticks != "" {
+		tickList = strings.Split(*ticks, ",")
+	}
+	rows, err := export.Collect(tickList)
+	if err != nil {
+		logrus.Fatalf("export err: %v", err)
+	}
+	checksum, err := export.WriteFile(*out, *format, *block, rows)
+	if err != nil {
+		logrus.Fatalf("write snapshot err: %v", err)
+	}
+	logrus.Infof("exported %d rows at block %d to %s, sha256 %s", len(rows), *block, *out, checksum)
+}
@@ -1,33 +1,195 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"rose-scriptions-open-indexer/chain"
+	"rose-scriptions-open-indexer/config"
 	"rose-scriptions-open-indexer/core"
 	"rose-scriptions-open-indexer/core/model"
-	"sync"
+	"rose-scriptions-open-indexer/metrics"
+	"rose-scriptions-open-indexer/snapshot"
+	"rose-scriptions-open-indexer/storage"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-const (
-	EnvChainUrl = "CHAIN_URL"
-)
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+var commands = []*command{
+	{"run", "[-from-snapshot file [-verify-snapshot] | -sync-from url] [-snapshot-root 0x..]   follow the chain and serve the apis", runRun},
+	{"backfill", "-from N -to M   index a block range, rebuilding the checkpoints above N", runBackfill},
+	{"rewind", "-to N   roll the saved state back to block N", runRewind},
+	{"inspect", "tx <hash> | block <n> | root [n] | invariants   print what was indexed, a state root or accounting errors", runInspect},
+	{"decode", "[-from] [-to] <calldata | data: uri | tx hash>   dry-run an inscription against the saved state", runDecode},
+	{"build", "-op -tick [-max -lim | -amt] [-from -to]   encode and validate rrc-20 calldata", runBuild},
+	{"diverge", "[-from N] <source> <source>   find the first block two indexers disagree on", runDiverge},
+	{"snapshot", "[-out file]   write a snapshot of the latest checkpoint", runSnapshot},
+	{"export", "-block N [-tick] [-format] [-out]   write a holder snapshot", runExport},
+	{"version", "print the version", runVersion},
+}
 
 func main() {
-	chainUrl := "https://emerald.oasis.dev"
-	bc, err := chain.NewBlockchainClient(chainUrl)
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nwithout a command the indexer runs, the configuration is read from the environment\n")
+}
+
+// App holds the config and storage shared by the commands
+type App struct {
+	cfg   *config.Config
+	store storage.Store
+	bc    *chain.BlockchainClient
+	// snapshots is opened on first use
+	snapshots *snapshot.Dir
+	// genesis is the height the indexer starts from without a checkpoint
+	genesis uint64
+	// readOnly replays blocks without saving checkpoints or snapshots, for commands that only read history
+	readOnly bool
+}
+
+func newApp() *App {
+	cfg, err := config.Load()
 	if err != nil {
-		logrus.Fatalf("Failed to create client: %v", err)
+		logrus.Fatalf("Invalid config: %v", err)
+	}
+	core.StrictParsingHeight = cfg.StrictParsingHeight
+
+	store, err := storage.Open(cfg.StorageBackend, cfg.DataDir, cfg.CheckpointKeep)
+	if err != nil {
+		logrus.Fatalf("Failed to open storage: %v", err)
+	}
+	app := &App{
+		cfg:     cfg,
+		store:   store,
+		genesis: core.LatestBlockNumber,
+	}
+	if cfg.InvariantMode != config.InvariantModeOff {
+		core.OnBlockCommitted(app.checkInvariants)
+	}
+	return app
+}
+
+// checkInvariants checks the ticks of the block, in halt mode a violation exits before the block is checkpointed
+func (a *App) checkInvariants(result *core.BlockResult) {
+	ticks := result.Ticks()
+	if len(ticks) == 0 {
+		return
+	}
+	violations := core.CheckInvariants(ticks)
+	for _, violation := range violations {
+		metrics.InvariantViolations.WithLabelValues(violation.Invariant).Inc()
+		logrus.Error(violation)
+	}
+	if len(violations) > 0 && a.cfg.InvariantMode == config.InvariantModeHalt {
+		logrus.Fatalf("halting on %d invariant violations at block %d", len(violations), result.Number)
+	}
+}
+
+// client dials the chain on first use, so offline commands don't need it
+func (a *App) client() *chain.BlockchainClient {
+	if a.bc == nil {
+		bc, err := chain.NewBlockchainClient(a.cfg.ChainURL)
+		if err != nil {
+			logrus.Fatalf("Failed to create client: %v", err)
+		}
+		a.bc = bc
 	}
+	return a.bc
+}
 
-	var wg sync.WaitGroup
+// restore imports the latest checkpoint at or below height, the genesis state is kept without one
+func (a *App) restore(height uint64) {
+	if height < a.genesis {
+		logrus.Fatalf("block %d is before the first indexed block %d", height, a.genesis+1)
+	}
+	state, err := a.store.LoadAt(height)
+	if errors.Is(err, storage.ErrNotFound) {
+		logrus.Infof("no checkpoint at or below %d, starting from %d", height, a.genesis)
+		return
+	} else if err != nil {
+		logrus.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	core.ImportState(state)
+	metrics.SetIndexedHeight(core.LatestBlockNumber)
+	logrus.Infof("restored checkpoint %d", core.LatestBlockNumber)
+}
 
-	wg.Add(1)
+// latestCheckpoint returns the height of the latest checkpoint, genesis without one
+func (a *App) latestCheckpoint() uint64 {
+	heights, err := a.store.Heights()
+	if err != nil {
+		logrus.Fatalf("Failed to list checkpoints: %v", err)
+	}
+	if len(heights) == 0 {
+		return a.genesis
+	}
+	return heights[len(heights)-1]
+}
 
-	go startChainFetcher(bc, &wg)
+// checkpoint saves the state every CheckpointInterval blocks, or always if force is set.
+// Stores that can save the changed entries only get the whole state when they need it.
+func (a *App) checkpoint(force bool) {
+	if a.readOnly || (!force && core.LatestBlockNumber%a.cfg.CheckpointInterval != 0) {
+		return
+	}
+	err := storage.ErrFullSave
+	if store, ok := a.store.(storage.ChangeStore); ok {
+		err = store.SaveChanges(core.ExportChanges())
+	}
+	if errors.Is(err, storage.ErrFullSave) {
+		err = a.store.Save(core.ExportState())
+	}
+	if err != nil {
+		logrus.Errorf("save checkpoint %d err: %v", core.LatestBlockNumber, err)
+		return
+	}
+	logrus.Infof("saved checkpoint %d", core.LatestBlockNumber)
+}
 
-	wg.Wait()
+// syncTo applies blocks until target, retrying failed blocks
+func (a *App) syncTo(target uint64) {
+	for core.LatestBlockNumber < target {
+		i := core.LatestBlockNumber + 1
+		bcinfo, err := getBlockInfo(a.client(), i)
+		if err != nil {
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		if err := core.HandleNewBlock(bcinfo); err != nil {
+			logrus.Errorf("HandleNewBlock %d err: %v", i, err)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+		a.checkpoint(false)
+		a.snapshot()
+	}
 }
 
 func getBlockInfo(bc *chain.BlockchainClient, bcNumber uint64) (*model.ChainBlock, error) {
@@ -43,37 +205,3 @@ func getBlockInfo(bc *chain.BlockchainClient, bcNumber uint64) (*model.ChainBloc
 		}
 	}
 }
-
-func startChainFetcher(bc *chain.BlockchainClient, wg *sync.WaitGroup) {
-	for {
-		bcNumber, err := bc.GetLatestBlockNumber()
-		if err != nil {
-			logrus.Errorf("GetLatestBlockNumber err: %v", err)
-			time.Sleep(3 * time.Second)
-			continue
-		}
-		logrus.Infof("lastDBNumber: %d, latestChainNumber: %d", core.LatestBlockNumber, bcNumber)
-		if core.LatestBlockNumber == uint64(bcNumber) {
-			time.Sleep(3 * time.Second)
-			continue
-		}
-
-		for i := core.LatestBlockNumber + 1; i <= uint64(bcNumber); i++ {
-			if bcinfo, err := getBlockInfo(bc, i); err != nil {
-				logrus.Errorf("GetBlock %d err: %v", i, err)
-				time.Sleep(1 * time.Second)
-				continue
-			} else {
-				logrus.Infof("HandleNewBlock %d, trx %d,receipts len %d, receipts %v ", i, len(bcinfo.Txs), len(bcinfo.Receipts), bcinfo.Receipts)
-				if err := core.HandleNewBlock(bcinfo); err != nil {
-					logrus.Errorf("HandleNewBlock %d err: %v", i, err)
-					time.Sleep(1 * time.Second)
-					break
-				} else {
-					logrus.Infof("HandleNewBlock %d success", i)
-				}
-			}
-		}
-	}
-	wg.Add(-1)
-}
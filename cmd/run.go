@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"rose-scriptions-open-indexer/api"
+	"rose-scriptions-open-indexer/attest"
+	"rose-scriptions-open-indexer/config"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/events"
+	"rose-scriptions-open-indexer/health"
+	"rose-scriptions-open-indexer/metrics"
+	"rose-scriptions-open-indexer/rpc"
+	"rose-scriptions-open-indexer/statesync"
+	"rose-scriptions-open-indexer/webhook"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runRun restores the latest checkpoint or a snapshot, serves the apis and follows the chain
+func runRun(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	fromSnapshot := flags.String("from-snapshot", "", "start from a snapshot file instead of the latest checkpoint")
+	verifySnapshot := flags.Bool("verify-snapshot", false, "rebuild the state root of the snapshot and compare it with the one it claims")
+	snapshotRoot := flags.String("snapshot-root", "", "state root the snapshot must hash to, e.g. from an attestation, implies -verify-snapshot")
+	syncFrom := flags.String("sync-from", "", "api url of an instance to download the latest snapshot from when the checkpoints are behind it")
+	syncOperator := flags.String("sync-operator", "", "operator address whose attestation of the synced snapshot provides its state root")
+	insecureSync := flags.Bool("insecure-sync", false, "sync without a trusted state root, trusting the source")
+	flags.Parse(args)
+	if *fromSnapshot != "" && *syncFrom != "" {
+		fmt.Fprintln(os.Stderr, "run: -from-snapshot and -sync-from are exclusive")
+		os.Exit(2)
+	}
+	if *syncFrom != "" && *snapshotRoot == "" && *syncOperator == "" && !*insecureSync {
+		fmt.Fprintln(os.Stderr, "run: -sync-from needs -snapshot-root or -sync-operator to verify the snapshot, or -insecure-sync")
+		os.Exit(2)
+	}
+
+	app := newApp()
+	switch {
+	case *fromSnapshot != "":
+		app.loadSnapshot(*fromSnapshot, *verifySnapshot, *snapshotRoot)
+	case *syncFrom != "":
+		app.syncSnapshot(*syncFrom, *snapshotRoot, *syncOperator)
+	default:
+		app.restore(math.MaxUint64)
+	}
+
+	server := api.NewServer(app.cfg.APIAddr)
+	hub := events.NewHub(events.DefaultHistorySize)
+	server.Handle("/api/v1/events/sse", http.HandlerFunc(hub.ServeSSE))
+	server.Handle("/api/v1/events/ws", http.HandlerFunc(hub.ServeWebSocket))
+	server.Handle("/metrics", metrics.Handler())
+
+	monitor := health.NewMonitor(app.cfg.HealthMaxLag, app.cfg.HealthStallTimeout)
+	server.Handle("/healthz", http.HandlerFunc(monitor.ServeLiveness))
+	server.Handle("/readyz", http.HandlerFunc(monitor.ServeReadiness))
+
+	webhookStore, err := webhook.NewStore(app.cfg.WebhookStore)
+	if err != nil {
+		logrus.Fatalf("Failed to load webhooks: %v", err)
+	}
+	webhookStore.AllowPrivate = app.cfg.WebhookAllowPrivate
+	dispatcher, err := webhook.NewDispatcher(webhookStore, app.cfg.WebhookDeadLetter, app.cfg.WebhookDeliveries)
+	if err != nil {
+		logrus.Fatalf("Failed to load webhook deliveries: %v", err)
+	}
+	if app.cfg.WebhookAdminToken != "" {
+		webhook.NewHandler(webhookStore, dispatcher, app.cfg.WebhookAdminToken).Register(server.Handle)
+	} else {
+		logrus.Warnf("%s is not set, the webhook api is disabled", config.EnvWebhookAdminToken)
+	}
+	statesync.NewHandler(app.snapshotDir(), statesync.DefaultChunkSize).Register(server.Handle)
+	if app.cfg.AttestationKeyFile != "" {
+		attest.NewHandler(attest.NewAttester(app.signer())).Register(server.Handle)
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			logrus.Fatalf("api server err: %v", err)
+		}
+	}()
+
+	grpcServer := rpc.NewServer(app.cfg.GRPCAddr)
+	go func() {
+		if err := grpcServer.ListenAndServe(); err != nil {
+			logrus.Fatalf("grpc server err: %v", err)
+		}
+	}()
+
+	metrics.SetIndexedHeight(core.LatestBlockNumber)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go app.startChainFetcher(monitor, &wg)
+
+	wg.Wait()
+}
+
+// chainID returns the configured chain id, asking the rpc if it is not set
+func (a *App) chainID() uint64 {
+	if a.cfg.ChainID != 0 {
+		return a.cfg.ChainID
+	}
+	chainID, err := a.client().GetChainID()
+	if err != nil {
+		logrus.Fatalf("Failed to get chain id: %v", err)
+	}
+	return chainID
+}
+
+// signer loads the attestation key
+func (a *App) signer() *attest.Signer {
+	chainID := a.chainID()
+	signer, err := attest.NewSigner(a.cfg.AttestationKeyFile, chainID, a.cfg.AttestationInterval)
+	if err != nil {
+		logrus.Fatalf("Failed to load attestation signer: %v", err)
+	}
+	logrus.Infof("signing state roots of every %d blocks as %s on chain %d", signer.Interval, signer.Address(), chainID)
+	return signer
+}
+
+func (a *App) startChainFetcher(monitor *health.Monitor, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	bc := a.client()
+	for {
+		bcNumber, err := bc.GetLatestBlockNumber()
+		if err != nil {
+			logrus.Errorf("GetLatestBlockNumber err: %v", err)
+			monitor.RecordRPCError(err)
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		metrics.SetChainHead(uint64(bcNumber))
+		monitor.RecordHead(uint64(bcNumber))
+		logrus.Infof("lastDBNumber: %d, latestChainNumber: %d", core.LatestBlockNumber, bcNumber)
+		if core.LatestBlockNumber == uint64(bcNumber) {
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		for i := core.LatestBlockNumber + 1; i <= uint64(bcNumber); i++ {
+			if bcinfo, err := getBlockInfo(bc, i); err != nil {
+				logrus.Errorf("GetBlock %d err: %v", i, err)
+				monitor.RecordError(err)
+				time.Sleep(1 * time.Second)
+				continue
+			} else {
+				logrus.Infof("HandleNewBlock %d, trx %d,receipts len %d, receipts %v ", i, len(bcinfo.Txs), len(bcinfo.Receipts), bcinfo.Receipts)
+				if err := core.HandleNewBlock(bcinfo); err != nil {
+					logrus.Errorf("HandleNewBlock %d err: %v", i, err)
+					monitor.RecordError(err)
+					time.Sleep(1 * time.Second)
+					break
+				} else {
+					logrus.Infof("HandleNewBlock %d success", i)
+					a.checkpoint(false)
+					a.snapshot()
+				}
+			}
+		}
+	}
+}
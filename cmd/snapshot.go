@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"rose-scriptions-open-indexer/attest"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/merkle"
+	"rose-scriptions-open-indexer/metrics"
+	"rose-scriptions-open-indexer/snapshot"
+	"rose-scriptions-open-indexer/statesync"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syncAttempts is the number of times a sync starts over when the snapshot is rotated out
+const syncAttempts = 3
+
+// runSnapshot writes a snapshot of the latest checkpoint and prints its header
+func runSnapshot(args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := flags.String("out", "", "output file, the next one in SNAPSHOT_DIR by default")
+	flags.Parse(args)
+
+	app := newApp()
+	app.restore(math.MaxUint64)
+
+	var header *snapshot.Header
+	var err error
+	if *out != "" {
+		header, err = snapshot.Write(*out, core.ExportState())
+	} else {
+		header, err = app.snapshotDir().Write(core.ExportState())
+	}
+	if err != nil {
+		logrus.Fatalf("write snapshot err: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(header); err != nil {
+		logrus.Fatalf("encode err: %v", err)
+	}
+}
+
+func (a *App) snapshotDir() *snapshot.Dir {
+	if a.snapshots == nil {
+		dir, err := snapshot.NewDir(a.cfg.SnapshotDir, a.cfg.SnapshotKeep)
+		if err != nil {
+			logrus.Fatalf("Failed to open snapshot dir: %v", err)
+		}
+		a.snapshots = dir
+	}
+	return a.snapshots
+}
+
+// snapshot writes a snapshot every SnapshotInterval blocks
+func (a *App) snapshot() {
+	if a.readOnly || a.cfg.SnapshotInterval == 0 || core.LatestBlockNumber%a.cfg.SnapshotInterval != 0 {
+		return
+	}
+	header, err := a.snapshotDir().Write(core.ExportState())
+	if err != nil {
+		logrus.Errorf("write snapshot %d err: %v", core.LatestBlockNumber, err)
+		return
+	}
+	logrus.Infof("wrote snapshot %d, sha256 %s", header.Block, header.SHA256)
+}
+
+// loadSnapshot imports the snapshot at path in place of the checkpoints. With verify the state root is rebuilt
+// and compared with the one of the snapshot and with trustedRoot if it is set, a snapshot without one is refused.
+// The rest of the state is only checked against the content hash of the header.
+// The checkpoints above the snapshot are dropped and it is saved as one, so a restart continues from it.
+func (a *App) loadSnapshot(path string, verify bool, trustedRoot string) {
+	header, state, err := snapshot.Read(path)
+	if err != nil {
+		logrus.Fatalf("Failed to load snapshot %s: %v", path, err)
+	}
+	if header.Block < a.genesis {
+		logrus.Fatalf("snapshot %d is before the first indexed block %d", header.Block, a.genesis+1)
+	}
+	core.ImportState(state)
+	logrus.Infof("loaded snapshot %d, block hash %s, state root %s", header.Block, header.BlockHash, header.StateRoot)
+
+	if verify || trustedRoot != "" {
+		if header.StateRoot == merkle.EmptyRoot {
+			logrus.Fatalf("snapshot %d has no state root to verify", header.Block)
+		}
+		computed := core.ComputeStateRoot()
+		if computed != header.StateRoot {
+			logrus.Fatalf("snapshot state root is %s, the state hashes to %s", header.StateRoot, computed)
+		}
+		if trustedRoot != "" {
+			root, err := merkle.ParseHash(trustedRoot)
+			if err != nil {
+				logrus.Fatalf("invalid state root %s", trustedRoot)
+			}
+			if computed != root {
+				logrus.Fatalf("snapshot state hashes to %s, expected %s", computed, root)
+			}
+		}
+		logrus.Infof("verified snapshot state root %s", computed)
+	}
+
+	if err := a.store.Truncate(header.Block); err != nil {
+		logrus.Fatalf("Failed to truncate checkpoints: %v", err)
+	}
+	a.checkpoint(true)
+	metrics.SetIndexedHeight(core.LatestBlockNumber)
+}
+
+// syncSnapshot downloads the latest snapshot of the instance at url and loads it with its state root verified,
+// unless the checkpoints are already at or above it. A snapshot rotated out during the download is retried.
+// The state root is trusted from trustedRoot or from the attestation of the snapshot block signed by operator,
+// with neither the source is trusted.
+func (a *App) syncSnapshot(url string, trustedRoot string, operator string) {
+	client := statesync.NewClient(url)
+	for attempt := 1; ; attempt++ {
+		manifest, err := client.Manifest()
+		if err != nil {
+			logrus.Fatalf("Failed to get snapshot manifest from %s: %v", url, err)
+		}
+		heights, err := a.store.Heights()
+		if err != nil {
+			logrus.Fatalf("Failed to list checkpoints: %v", err)
+		}
+		if n := len(heights); n > 0 && heights[n-1] >= manifest.Header.Block {
+			logrus.Infof("checkpoint %d is not behind snapshot %d of %s", heights[n-1], manifest.Header.Block, url)
+			a.restore(math.MaxUint64)
+			return
+		}
+
+		if manifest.Header.StateRoot == merkle.EmptyRoot {
+			logrus.Fatalf("snapshot %d of %s has no state root to verify", manifest.Header.Block, url)
+		}
+		root := trustedRoot
+		if root == "" && operator != "" {
+			root = a.attestedRoot(url, manifest.Header, operator)
+		}
+		if root == "" {
+			logrus.Warnf("INSECURE: syncing snapshot %d without a trusted state root, %s could serve any state", manifest.Header.Block, url)
+		}
+
+		logrus.Infof("syncing snapshot %d from %s, %d chunks", manifest.Header.Block, url, len(manifest.Chunks))
+		path := a.snapshotDir().Path(manifest.Header.Block)
+		err = client.Download(manifest, path)
+		if errors.Is(err, statesync.ErrGone) && attempt < syncAttempts {
+			logrus.Warnf("snapshot %d was rotated out, starting over", manifest.Header.Block)
+			continue
+		} else if err != nil {
+			logrus.Fatalf("Failed to download snapshot %d: %v", manifest.Header.Block, err)
+		}
+		a.loadSnapshot(path, true, root)
+		return
+	}
+}
+
+// attestedRoot returns the state root of the snapshot of header as attested by operator, fetched from url
+func (a *App) attestedRoot(url string, header *snapshot.Header, operator string) string {
+	attestation, err := attest.Fetch(url, header.Block)
+	if err != nil {
+		logrus.Fatalf("Failed to get the attestation of snapshot %d from %s: %v", header.Block, url, err)
+	}
+	if err := attestation.Verify(operator); err != nil {
+		logrus.Fatalf("attestation of snapshot %d: %v", header.Block, err)
+	}
+	if chainID := a.chainID(); attestation.BlockNumber != header.Block || attestation.ChainID != chainID {
+		logrus.Fatalf("attestation is for block %d on chain %d, expected %d on %d", attestation.BlockNumber, attestation.ChainID, header.Block, chainID)
+	}
+	if !strings.EqualFold(attestation.BlockHash, header.BlockHash) {
+		logrus.Fatalf("attestation is for block hash %s, the snapshot for %s", attestation.BlockHash, header.BlockHash)
+	}
+	logrus.Infof("snapshot %d state root %s is attested by %s", header.Block, attestation.StateRoot, operator)
+	return attestation.StateRoot.String()
+}
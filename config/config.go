@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Invariant modes, what happens when the accounting check after a block fails
+const (
+	InvariantModeOff   = "off"
+	InvariantModeAlert = "alert"
+	InvariantModeHalt  = "halt"
+)
+
+const (
+	EnvChainUrl            = "CHAIN_URL"
+	EnvStrictParsingHeight = "STRICT_PARSING_HEIGHT"
+	EnvStorageBackend      = "STORAGE_BACKEND"
+	EnvDataDir             = "DATA_DIR"
+	EnvCheckpointInterval  = "CHECKPOINT_INTERVAL"
+	EnvCheckpointKeep      = "CHECKPOINT_KEEP"
+	EnvApiAddr             = "API_ADDR"
+	EnvGrpcAddr            = "GRPC_ADDR"
+	EnvWebhookStore        = "WEBHOOK_STORE"
+	EnvWebhookDeadLetter   = "WEBHOOK_DEAD_LETTER"
+	EnvWebhookDeliveries   = "WEBHOOK_DELIVERIES"
+	EnvWebhookAdminToken   = "WEBHOOK_ADMIN_TOKEN"
+	EnvWebhookAllowPrivate = "WEBHOOK_ALLOW_PRIVATE"
+	EnvHealthMaxLag        = "HEALTH_MAX_LAG"
+	EnvHealthStallTimeout  = "HEALTH_STALL_TIMEOUT"
+	EnvChainID             = "CHAIN_ID"
+	EnvAttestationKeyFile  = "ATTESTATION_KEY_FILE"
+	EnvAttestationInterval = "ATTESTATION_INTERVAL"
+	EnvInvariantMode       = "INVARIANT_MODE"
+	EnvSnapshotDir         = "SNAPSHOT_DIR"
+	EnvSnapshotInterval    = "SNAPSHOT_INTERVAL"
+	EnvSnapshotKeep        = "SNAPSHOT_KEEP"
+)
+
+// Config is shared by all commands, it is read from the environment
+type Config struct {
+	ChainURL            string
+	StrictParsingHeight uint64
+	// StorageBackend is file for json checkpoints or leveldb for the latest state as key-values
+	StorageBackend string
+	// DataDir holds the state checkpoints or the leveldb database
+	DataDir string
+	// CheckpointInterval is 1000 blocks for file checkpoints and 1 for leveldb by default
+	CheckpointInterval uint64
+	// CheckpointKeep is the number of checkpoints kept, 0 keeps all. Each one is the whole state.
+	CheckpointKeep int
+
+	APIAddr  string
+	GRPCAddr string
+
+	WebhookStore      string
+	WebhookDeadLetter string
+	// WebhookDeliveries is the delivery log, read back at the start
+	WebhookDeliveries string
+	// WebhookAdminToken protects the webhook api, which is disabled without it
+	WebhookAdminToken string
+	// WebhookAllowPrivate allows webhook urls on loopback, link-local and private addresses
+	WebhookAllowPrivate bool
+
+	HealthMaxLag       uint64
+	HealthStallTimeout time.Duration
+
+	// ChainID is signed in attestations, 0 asks the chain rpc
+	ChainID uint64
+	// AttestationKeyFile holds the hex secp256k1 key state roots are signed with, empty disables attestations
+	AttestationKeyFile  string
+	AttestationInterval uint64
+
+	// InvariantMode is off, alert to log and count violations or halt to also stop before saving the block
+	InvariantMode string
+
+	// SnapshotDir holds the snapshots written every SnapshotInterval blocks, 0 disables them
+	SnapshotDir      string
+	SnapshotInterval uint64
+	// SnapshotKeep is the number of snapshots kept, 0 keeps all
+	SnapshotKeep int
+}
+
+func Default() *Config {
+	return &Config{
+		ChainURL:            "https://emerald.oasis.dev",
+		StrictParsingHeight: math.MaxUint64,
+		StorageBackend:      "file",
+		DataDir:             "data",
+		CheckpointInterval:  1000,
+		CheckpointKeep:      3,
+		APIAddr:             ":8080",
+		GRPCAddr:            ":9090",
+		WebhookStore:        "webhooks.json",
+		WebhookDeadLetter:   "webhooks-dead-letter.jsonl",
+		WebhookDeliveries:   "webhooks-deliveries.jsonl",
+		HealthMaxLag:        10,
+		HealthStallTimeout:  5 * time.Minute,
+		AttestationInterval: 1,
+		InvariantMode:       InvariantModeOff,
+		SnapshotDir:         "snapshots",
+		SnapshotInterval:    100000,
+		SnapshotKeep:        2,
+	}
+}
+
+// Load returns the default config overridden by the environment
+func Load() (*Config, error) {
+	c := Default()
+	loadString(EnvChainUrl, &c.ChainURL)
+	loadString(EnvStorageBackend, &c.StorageBackend)
+	loadString(EnvDataDir, &c.DataDir)
+	loadString(EnvApiAddr, &c.APIAddr)
+	loadString(EnvGrpcAddr, &c.GRPCAddr)
+	loadString(EnvWebhookStore, &c.WebhookStore)
+	loadString(EnvWebhookDeadLetter, &c.WebhookDeadLetter)
+	loadString(EnvWebhookDeliveries, &c.WebhookDeliveries)
+	loadString(EnvWebhookAdminToken, &c.WebhookAdminToken)
+	loadString(EnvAttestationKeyFile, &c.AttestationKeyFile)
+	loadString(EnvInvariantMode, &c.InvariantMode)
+	loadString(EnvSnapshotDir, &c.SnapshotDir)
+
+	if err := loadBool(EnvWebhookAllowPrivate, &c.WebhookAllowPrivate); err != nil {
+		return nil, err
+	}
+	if err := loadUint(EnvStrictParsingHeight, &c.StrictParsingHeight); err != nil {
+		return nil, err
+	}
+	// leveldb only writes the changed entries, it checkpoints every block unless told otherwise
+	if c.StorageBackend == "leveldb" {
+		c.CheckpointInterval = 1
+	}
+	if err := loadUint(EnvCheckpointInterval, &c.CheckpointInterval); err != nil {
+		return nil, err
+	}
+	if c.CheckpointInterval == 0 {
+		return nil, fmt.Errorf("invalid %s: must be positive", EnvCheckpointInterval)
+	}
+	if value := os.Getenv(EnvCheckpointKeep); value != "" {
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", EnvCheckpointKeep, value)
+		}
+		c.CheckpointKeep = keep
+	}
+	if err := loadUint(EnvSnapshotInterval, &c.SnapshotInterval); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv(EnvSnapshotKeep); value != "" {
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", EnvSnapshotKeep, value)
+		}
+		c.SnapshotKeep = keep
+	}
+	if err := loadUint(EnvHealthMaxLag, &c.HealthMaxLag); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv(EnvHealthStallTimeout); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvHealthStallTimeout, err)
+		}
+		c.HealthStallTimeout = timeout
+	}
+	if err := loadUint(EnvChainID, &c.ChainID); err != nil {
+		return nil, err
+	}
+	if err := loadUint(EnvAttestationInterval, &c.AttestationInterval); err != nil {
+		return nil, err
+	}
+	if c.AttestationInterval == 0 {
+		return nil, fmt.Errorf("invalid %s: must be positive", EnvAttestationInterval)
+	}
+	switch c.InvariantMode {
+	case InvariantModeOff, InvariantModeAlert, InvariantModeHalt:
+	default:
+		return nil, fmt.Errorf("invalid %s: %s", EnvInvariantMode, c.InvariantMode)
+	}
+	return c, nil
+}
+
+func loadString(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func loadUint(key string, dst *uint64) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", key, err)
+	}
+	*dst = n
+	return nil
+}
+
+func loadBool(key string, dst *bool) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", key, err)
+	}
+	*dst = b
+	return nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import "rose-scriptions-open-indexer/core/model"
+
+// StateChanges are the entries changed between two calls of ExportChanges, for stores that save
+// incrementally. A nil value in Tokens, TokenHolders or Lists is an entry that was removed.
+// With Full set the changes are unknown, e.g. after ImportState, and the whole state has to be saved.
+type StateChanges struct {
+	Full bool
+	// From is the LatestBlockNumber of the previous ExportChanges, the state the changes apply to
+	From              uint64
+	LatestBlockNumber uint64
+	InscriptionNumber uint64
+	ImportedTo        uint64
+	Tokens            map[string]*model.Token
+	TokenHolders      map[string]map[string]*model.DDecimal
+	Lists             map[string]*model.ListedRecord
+	// Inscriptions, Records, BalanceChanges and BlockRoots are the entries appended since From
+	Inscriptions   []*model.Inscription
+	Records        []*model.RRC20
+	BalanceChanges []*model.BalanceChange
+	BlockRoots     []*model.BlockRoot
+}
+
+// changeSet holds the keys changed since the previous ExportChanges, guarded by stateLock.
+// It is only filled once ExportChanges was called, indexers saving whole states don't pay for it.
+type changeSet struct {
+	tracking bool
+	from     uint64
+	ticks    map[string]bool
+	holders  map[string]map[string]bool
+	lists    map[string]bool
+	journal  []*model.BalanceChange
+	// the lengths of the appended slices at the previous export
+	inscriptions int
+	records      int
+	roots        int
+}
+
+var changes = &changeSet{}
+
+// copy returns a backup of the change set for dry runs, the journal is only appended to
+func (c *changeSet) copy() *changeSet {
+	copied := *c
+	copied.ticks = make(map[string]bool, len(c.ticks))
+	for lowerTick := range c.ticks {
+		copied.ticks[lowerTick] = true
+	}
+	copied.holders = make(map[string]map[string]bool, len(c.holders))
+	for lowerTick, owners := range c.holders {
+		copied.holders[lowerTick] = make(map[string]bool, len(owners))
+		for owner := range owners {
+			copied.holders[lowerTick][owner] = true
+		}
+	}
+	copied.lists = make(map[string]bool, len(c.lists))
+	for hash := range c.lists {
+		copied.lists[hash] = true
+	}
+	return &copied
+}
+
+func markTokenChanged(lowerTick string) {
+	if changes.tracking {
+		changes.ticks[lowerTick] = true
+	}
+}
+
+func markBalanceChanged(lowerTick string, owner string, change *model.BalanceChange) {
+	if !changes.tracking {
+		return
+	}
+	changes.ticks[lowerTick] = true
+	if changes.holders[lowerTick] == nil {
+		changes.holders[lowerTick] = make(map[string]bool)
+	}
+	changes.holders[lowerTick][owner] = true
+	changes.journal = append(changes.journal, change)
+}
+
+func markListChanged(hash string) {
+	if changes.tracking {
+		changes.lists[hash] = true
+	}
+}
+
+// resetChanges starts tracking from the current state, the caller holds the lock
+func resetChanges(tracking bool) {
+	changes = &changeSet{
+		tracking:     tracking,
+		from:         LatestBlockNumber,
+		ticks:        make(map[string]bool),
+		holders:      make(map[string]map[string]bool),
+		lists:        make(map[string]bool),
+		inscriptions: len(inscriptions),
+		records:      len(rrc20Records),
+		roots:        len(blockRoots),
+	}
+}
+
+// ExportChanges returns the entries changed since the previous call. The first call, and the first
+// after ImportState, returns Full changes. Like ExportState it shares the indexed objects.
+func ExportChanges() *StateChanges {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	res := &StateChanges{
+		Full:              !changes.tracking,
+		From:              changes.from,
+		LatestBlockNumber: LatestBlockNumber,
+		InscriptionNumber: inscriptionNumber,
+		ImportedTo:        importedTo,
+	}
+	if !res.Full {
+		res.Tokens = make(map[string]*model.Token, len(changes.ticks))
+		for lowerTick := range changes.ticks {
+			res.Tokens[lowerTick] = tokens[lowerTick]
+		}
+		res.TokenHolders = make(map[string]map[string]*model.DDecimal, len(changes.holders))
+		for lowerTick, owners := range changes.holders {
+			res.TokenHolders[lowerTick] = make(map[string]*model.DDecimal, len(owners))
+			for owner := range owners {
+				res.TokenHolders[lowerTick][owner] = tokenHolders[lowerTick][owner]
+			}
+		}
+		res.Lists = make(map[string]*model.ListedRecord, len(changes.lists))
+		for hash := range changes.lists {
+			res.Lists[hash] = lists[hash]
+		}
+		res.Inscriptions = append([]*model.Inscription(nil), inscriptions[changes.inscriptions:]...)
+		res.Records = append([]*model.RRC20(nil), rrc20Records[changes.records:]...)
+		res.BalanceChanges = changes.journal
+		res.BlockRoots = append([]*model.BlockRoot(nil), blockRoots[changes.roots:]...)
+	}
+	resetChanges(true)
+	return res
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// blockRoots is the state commitment of each indexed block in block order, guarded by stateLock
+	blockRoots []*model.BlockRoot
+
+	// tickRoots caches the balance tree root of each tick, dirtyTicks are the ticks changed since
+	tickRoots  = make(map[string]merkle.Hash)
+	dirtyTicks = make(map[string]bool)
+)
+
+// commitBlock computes the state root after block and appends it to blockRoots, the caller holds the lock
+func commitBlock(block *model.ChainBlock) *model.BlockRoot {
+	root := &model.BlockRoot{
+		Number:       block.Number,
+		Hash:         block.Hash,
+		TokensRoot:   tokensRoot(),
+		BalancesRoot: balancesRoot(),
+		ListsRoot:    listsRoot(),
+	}
+	root.StateRoot = merkle.StateRoot(root.TokensRoot, root.BalancesRoot, root.ListsRoot)
+	blockRoots = append(blockRoots, root)
+	return root
+}
+
+func markTickDirty(lowerTick string) {
+	dirtyTicks[lowerTick] = true
+}
+
+// tokenLeaf commits to the fields a token is indexed with, CompletedAt is left out
+// since it is the local time it was minted out and Progress since it is derived from Minted
+func tokenLeaf(lowerTick string, token *model.Token) merkle.Hash {
+	return merkle.LeafHash(merkle.Encode(
+		lowerTick,
+		token.Tick,
+		strconv.FormatUint(token.Number, 10),
+		strconv.Itoa(token.Precision),
+		token.Max.String(),
+		token.Limit.String(),
+		token.Minted.String(),
+		strconv.FormatInt(int64(token.Holders), 10),
+		strconv.FormatInt(int64(token.Trxs), 10),
+		strconv.FormatUint(token.CreatedAt, 10),
+		token.DeployAddress,
+		token.DeployHash,
+	))
+}
+
+func listLeaf(listRec *model.ListedRecord) merkle.Hash {
+	return merkle.LeafHash(merkle.Encode(
+		listRec.Hash,
+		listRec.Tick,
+		listRec.OriginAddr,
+		listRec.ListedTo,
+		listRec.Amount.String(),
+		strconv.FormatUint(listRec.ListedTs, 10),
+	))
+}
+
+func tokensRoot() merkle.Hash {
+	lowerTicks := make([]string, 0, len(tokens))
+	for lowerTick := range tokens {
+		lowerTicks = append(lowerTicks, lowerTick)
+	}
+	sort.Strings(lowerTicks)
+	leaves := make([]merkle.Hash, 0, len(lowerTicks))
+	for _, lowerTick := range lowerTicks {
+		leaves = append(leaves, tokenLeaf(lowerTick, tokens[lowerTick]))
+	}
+	return merkle.Root(leaves)
+}
+
+func listsRoot() merkle.Hash {
+	hashes := make([]string, 0, len(lists))
+	for hash := range lists {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	leaves := make([]merkle.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		leaves = append(leaves, listLeaf(lists[hash]))
+	}
+	return merkle.Root(leaves)
+}
+
+// balancesRoot commits to the non-zero balances, the tick roots are only rebuilt for the changed ticks
+func balancesRoot() merkle.Hash {
+	for lowerTick := range dirtyTicks {
+		root := merkle.Root(balanceLeaves(tokenHolders[lowerTick]))
+		if root == merkle.EmptyRoot {
+			delete(tickRoots, lowerTick)
+		} else {
+			tickRoots[lowerTick] = root
+		}
+		delete(dirtyTicks, lowerTick)
+	}
+	return rootOfTicks(tickRoots)
+}
+
+// lowerHolders returns holders keyed by lowercase address. Settlements before the strict parsing height credit
+// the checksummed buyer, those balances are folded in so the leaves never commit an address proofs don't look up.
+func lowerHolders(holders map[string]*model.DDecimal) map[string]*model.DDecimal {
+	lower := true
+	for owner := range holders {
+		if owner != strings.ToLower(owner) {
+			lower = false
+			break
+		}
+	}
+	if lower {
+		return holders
+	}
+	folded := make(map[string]*model.DDecimal, len(holders))
+	for owner, balance := range holders {
+		if balance == nil {
+			continue
+		}
+		owner = strings.ToLower(owner)
+		if existing, ok := folded[owner]; ok {
+			balance = existing.Add(balance)
+		}
+		folded[owner] = balance
+	}
+	return folded
+}
+
+// balanceOwners returns the owners with a non-zero balance sorted by address
+func balanceOwners(holders map[string]*model.DDecimal) []string {
+	owners := make([]string, 0, len(holders))
+	for owner, balance := range holders {
+		if balance != nil && balance.Sign() != 0 {
+			owners = append(owners, owner)
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// balanceLeaves returns the leaves of the non-zero balances sorted by address
+func balanceLeaves(holders map[string]*model.DDecimal) []merkle.Hash {
+	holders = lowerHolders(holders)
+	owners := balanceOwners(holders)
+	leaves := make([]merkle.Hash, 0, len(owners))
+	for _, owner := range owners {
+		leaves = append(leaves, merkle.BalanceLeaf(owner, holders[owner].String()))
+	}
+	return leaves
+}
+
+func rootOfTicks(roots map[string]merkle.Hash) merkle.Hash {
+	_, leaves := tickLeaves(roots)
+	return merkle.Root(leaves)
+}
+
+// tickLeaves returns the ticks and leaves of the balances tree sorted by tick
+func tickLeaves(roots map[string]merkle.Hash) ([]string, []merkle.Hash) {
+	lowerTicks := make([]string, 0, len(roots))
+	for lowerTick := range roots {
+		lowerTicks = append(lowerTicks, lowerTick)
+	}
+	sort.Strings(lowerTicks)
+	leaves := make([]merkle.Hash, 0, len(lowerTicks))
+	for _, lowerTick := range lowerTicks {
+		leaves = append(leaves, merkle.TickLeaf(lowerTick, roots[lowerTick]))
+	}
+	return lowerTicks, leaves
+}
+
+// resetTickRoots drops the cached tick roots after the state was replaced
+func resetTickRoots() {
+	historicalRoots.reset()
+	tickRoots = make(map[string]merkle.Hash)
+	dirtyTicks = make(map[string]bool, len(tokenHolders))
+	for lowerTick := range tokenHolders {
+		dirtyTicks[lowerTick] = true
+	}
+}
+
+// blockRootIndex returns the index of the root of block in blockRoots, -1 if there is none
+func blockRootIndex(block uint64) int {
+	i := sort.Search(len(blockRoots), func(i int) bool {
+		return blockRoots[i].Number >= block
+	})
+	if i == len(blockRoots) || blockRoots[i].Number != block {
+		return -1
+	}
+	return i
+}
+
+// QueryBlockRoot returns the state commitment of block, ok is false if it has none,
+// e.g. when it was indexed before state roots were kept
+func QueryBlockRoot(block uint64) (*model.BlockRoot, bool, error) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if block > LatestBlockNumber {
+		return nil, false, ErrorBlockNotIndexed
+	}
+	i := blockRootIndex(block)
+	if i < 0 {
+		return nil, false, nil
+	}
+	root := *blockRoots[i]
+	return &root, true, nil
+}
+
+// ComputeStateRoot rebuilds the state root of the current state from scratch,
+// e.g. to check a loaded snapshot against the root it claims
+func ComputeStateRoot() merkle.Hash {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	resetTickRoots()
+	return merkle.StateRoot(tokensRoot(), balancesRoot(), listsRoot())
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"testing"
+)
+
+func TestBalanceLeavesFoldAddresses(t *testing.T) {
+	const (
+		lower   = "0x00000000000000000000000000000000000000ab"
+		checked = "0x00000000000000000000000000000000000000AB"
+	)
+	mixed := map[string]*model.DDecimal{lower: decimal(t, "5"), checked: decimal(t, "3")}
+	folded := map[string]*model.DDecimal{lower: decimal(t, "8")}
+	if merkle.Root(balanceLeaves(mixed)) != merkle.Root(balanceLeaves(folded)) {
+		t.Fatal("leaves of a checksummed holder differ from the lowercase one")
+	}
+	if owners := balanceOwners(lowerHolders(mixed)); len(owners) != 1 || owners[0] != lower {
+		t.Fatalf("owners = %v", owners)
+	}
+}
+
+func TestTickRootsCache(t *testing.T) {
+	cache := &tickRootsCache{roots: make(map[uint64]map[string]merkle.Hash)}
+	builds := 0
+	build := func() map[string]merkle.Hash {
+		builds++
+		return map[string]merkle.Hash{}
+	}
+	for block := uint64(0); block < historicalRootsSize; block++ {
+		cache.get(block, build)
+	}
+	cache.get(0, build)
+	if builds != historicalRootsSize {
+		t.Fatalf("builds = %d, want %d", builds, historicalRootsSize)
+	}
+	// block 1 is now the least recently used
+	cache.get(historicalRootsSize, build)
+	cache.get(0, build)
+	cache.get(1, build)
+	if builds != historicalRootsSize+2 {
+		t.Fatalf("builds = %d, want %d", builds, historicalRootsSize+2)
+	}
+	cache.reset()
+	cache.get(0, build)
+	if builds != historicalRootsSize+3 {
+		t.Fatalf("builds after reset = %d, want %d", builds, historicalRootsSize+3)
+	}
+}
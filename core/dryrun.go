@@ -0,0 +1,229 @@
+package core
+
+import (
+	"encoding/hex"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"strings"
+	"time"
+)
+
+// DryRunResult explains how a transaction would be indexed on top of the current state
+type DryRunResult struct {
+	// Block is the height the transaction is evaluated at, the next block to index
+	Block       uint64
+	Strict      bool
+	DataURI     string `json:",omitempty"`
+	ContentType string `json:",omitempty"`
+	Content     string `json:",omitempty"`
+	// DecodeError is why the calldata is not an inscription
+	DecodeError string `json:",omitempty"`
+	// Protocol holds the parsed json fields, ParseCode the strict parsing result
+	Protocol  map[string]string `json:",omitempty"`
+	ParseCode model.ValideCode
+	// Record is the rrc-20 operation, nil if the inscription is not one
+	Record         *model.RRC20 `json:",omitempty"`
+	Valid          model.ValideCode
+	Message        string `json:",omitempty"`
+	BalanceChanges []*model.BalanceChange
+	// Error is a failure that would stop indexing of the block
+	Error string `json:",omitempty"`
+}
+
+// DryRun decodes trx and validates it against the current state as if it were in the next block.
+// The state is restored afterwards, the block listeners are not called.
+func DryRun(trx *model.ChainTransaction) *DryRunResult {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	block := LatestBlockNumber + 1
+	copied := *trx
+	trx = &copied
+	trx.Block = block
+	res := &DryRunResult{
+		Block:          block,
+		Strict:         block >= StrictParsingHeight,
+		BalanceChanges: make([]*model.BalanceChange, 0),
+	}
+
+	contentType, content, err := decodeInput(trx)
+	if err != nil {
+		res.DecodeError = err.Error()
+		return res
+	}
+	if input, err := hex.DecodeString(trx.Input[2:]); err == nil {
+		res.DataURI = string(input)
+	}
+	res.ContentType = contentType
+	res.Content = content
+
+	trimmed := strings.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		protoData, parseCode, err := parseContent(content, res.Strict)
+		if err != nil {
+			res.DecodeError = err.Error()
+		}
+		res.Protocol = protoData
+		res.ParseCode = parseCode
+	}
+
+	backup := backupState()
+	defer restoreState(backup)
+
+	pendingResult = &BlockResult{Number: block, Timestamp: trx.Timestamp}
+	defer func() {
+		pendingResult = nil
+	}()
+
+	if _, err := handleTransaction(trx); err != nil {
+		res.Error = err.Error()
+	}
+	if len(pendingResult.Records) > 0 {
+		record := *pendingResult.Records[0]
+		res.Record = &record
+		res.Valid = record.Valid
+		res.Message = record.Valid.String()
+	}
+	res.BalanceChanges = append(res.BalanceChanges, pendingResult.BalanceChanges...)
+	return res
+}
+
+// stateBackup holds copies of everything handleTransaction may change
+type stateBackup struct {
+	inscriptionNumber  uint64
+	inscriptions       []*model.Inscription
+	inscriptionsByHash map[string]*model.Inscription
+	rrc20Records       []*model.RRC20
+	recordsByHash      map[string][]*model.RRC20
+	recordsByTick      map[string][]*model.RRC20
+	recordsByAddress   map[string][]*model.RRC20
+	tokens             map[string]*model.Token
+	tokenHolders       map[string]map[string]*model.DDecimal
+	balances           map[string]map[string]*model.DDecimal
+	lists              map[string]*model.ListedRecord
+	tickSkeletons      map[string]string
+	normalizedTicks    map[string]string
+	balanceJournal     map[string]map[string][]*model.BalanceChange
+	changesByTx        map[string][]*model.BalanceChange
+	changesByBlock     map[uint64][]*model.BalanceChange
+	tickRoots          map[string]merkle.Hash
+	dirtyTicks         map[string]bool
+	changes            *changeSet
+}
+
+// backupState copies the mutable state, the caller holds the lock.
+// Slices are only appended to, so their headers are enough.
+func backupState() *stateBackup {
+	backup := &stateBackup{
+		inscriptionNumber:  inscriptionNumber,
+		inscriptions:       inscriptions,
+		inscriptionsByHash: make(map[string]*model.Inscription, len(inscriptionsByHash)),
+		rrc20Records:       rrc20Records,
+		recordsByHash:      copyIndex(recordsByHash),
+		recordsByTick:      copyIndex(recordsByTick),
+		recordsByAddress:   copyIndex(recordsByAddress),
+		tokens:             make(map[string]*model.Token, len(tokens)),
+		tokenHolders:       make(map[string]map[string]*model.DDecimal, len(tokenHolders)),
+		balances:           make(map[string]map[string]*model.DDecimal, len(balances)),
+		lists:              make(map[string]*model.ListedRecord, len(lists)),
+		tickSkeletons:      make(map[string]string, len(tickSkeletons)),
+		normalizedTicks:    make(map[string]string, len(normalizedTicks)),
+		balanceJournal:     make(map[string]map[string][]*model.BalanceChange, len(balanceJournal)),
+		changesByTx:        copyIndex(changesByTx),
+		changesByBlock:     copyIndex(changesByBlock),
+		tickRoots:          make(map[string]merkle.Hash, len(tickRoots)),
+		dirtyTicks:         make(map[string]bool, len(dirtyTicks)),
+		changes:            changes.copy(),
+	}
+	for hash, inscription := range inscriptionsByHash {
+		backup.inscriptionsByHash[hash] = inscription
+	}
+	for lowerTick, token := range tokens {
+		copied := *token
+		backup.tokens[lowerTick] = &copied
+	}
+	for lowerTick, holders := range tokenHolders {
+		backup.tokenHolders[lowerTick] = copyBalances(holders)
+	}
+	for owner, ownerBalances := range balances {
+		backup.balances[owner] = copyBalances(ownerBalances)
+	}
+	for hash, listRec := range lists {
+		backup.lists[hash] = listRec
+	}
+	for skeleton, lowerTick := range tickSkeletons {
+		backup.tickSkeletons[skeleton] = lowerTick
+	}
+	for normalized, lowerTick := range normalizedTicks {
+		backup.normalizedTicks[normalized] = lowerTick
+	}
+	for lowerTick, owners := range balanceJournal {
+		copied := make(map[string][]*model.BalanceChange, len(owners))
+		for owner, changes := range owners {
+			copied[owner] = changes
+		}
+		backup.balanceJournal[lowerTick] = copied
+	}
+	for lowerTick, root := range tickRoots {
+		backup.tickRoots[lowerTick] = root
+	}
+	for lowerTick := range dirtyTicks {
+		backup.dirtyTicks[lowerTick] = true
+	}
+	return backup
+}
+
+// copyIndex copies an index of append-only slices, their headers are enough
+func copyIndex[K comparable, V any](index map[K][]V) map[K][]V {
+	copied := make(map[K][]V, len(index))
+	for key, values := range index {
+		copied[key] = values
+	}
+	return copied
+}
+
+// restoreState puts a backup back, the token objects are restored in place
+// since the state holds pointers to them
+func restoreState(backup *stateBackup) {
+	inscriptionNumber = backup.inscriptionNumber
+	inscriptions = backup.inscriptions
+	inscriptionsByHash = backup.inscriptionsByHash
+	rrc20Records = backup.rrc20Records
+	recordsByHash = backup.recordsByHash
+	recordsByTick = backup.recordsByTick
+	recordsByAddress = backup.recordsByAddress
+	for lowerTick, token := range tokens {
+		if saved, ok := backup.tokens[lowerTick]; ok {
+			*token = *saved
+			backup.tokens[lowerTick] = token
+		}
+	}
+	tokens = backup.tokens
+	tokenHolders = backup.tokenHolders
+	balances = backup.balances
+	lists = backup.lists
+	tickSkeletons = backup.tickSkeletons
+	normalizedTicks = backup.normalizedTicks
+	balanceJournal = backup.balanceJournal
+	changesByTx = backup.changesByTx
+	changesByBlock = backup.changesByBlock
+	tickRoots = backup.tickRoots
+	dirtyTicks = backup.dirtyTicks
+	changes = backup.changes
+}
+
+// DryRunCall builds the calldata of call and validates it against the current state
+// as sent by from to to, the recipient of transfers and the market of listings
+func DryRunCall(call *model.RRC20Call, from string, to string) (*DryRunResult, model.ValideCode) {
+	calldata, code := call.Calldata()
+	if code != model.ValidCodeOK {
+		return nil, code
+	}
+	res := DryRun(&model.ChainTransaction{
+		From:      from,
+		To:        to,
+		Input:     calldata,
+		Timestamp: uint64(time.Now().Unix()),
+	})
+	return res, res.Valid
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestDryRunRestoresDirtyTicks(t *testing.T) {
+	defer ImportState(&State{})
+
+	const (
+		seller = "0x00000000000000000000000000000000000000aa"
+		buyer  = "0x00000000000000000000000000000000000000ab"
+	)
+	ImportState(&State{
+		Version:           StateVersion,
+		LatestBlockNumber: 10,
+		Tokens: map[string]*model.Token{"rose": {
+			Tick: "rose", Max: decimal(t, "10"), Limit: decimal(t, "5"), Minted: decimal(t, "5"), Holders: 1,
+		}},
+		TokenHolders: map[string]map[string]*model.DDecimal{"rose": {seller: decimal(t, "5")}},
+		Balances:     map[string]map[string]*model.DDecimal{seller: {"rose": decimal(t, "5")}},
+	})
+	root := ComputeStateRoot()
+
+	call := &model.RRC20Call{Operation: model.RRC20OperationTransfer, Tick: "rose", Amount: "2"}
+	if _, code := DryRunCall(call, seller, buyer); code != model.ValidCodeOK {
+		t.Fatalf("dry run = %d", code)
+	}
+	if len(dirtyTicks) != 0 {
+		t.Fatalf("dirty ticks after dry run = %v", dirtyTicks)
+	}
+	if got := ComputeStateRoot(); got != root {
+		t.Fatalf("state root after dry run = %s, want %s", got, root)
+	}
+}
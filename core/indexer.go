@@ -4,9 +4,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"math"
 	"math/big"
 	"rose-scriptions-open-indexer/core/model"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -21,12 +23,25 @@ var (
 
 	LatestBlockNumber uint64 = 10320518
 
-	inscriptionNumber uint64 = 0
-	rrc20Records      []*model.RRC20
-	tokens            = make(map[string]*model.Token)
-	tokenHolders      = make(map[string]map[string]*model.DDecimal)
-	balances          = make(map[string]map[string]*model.DDecimal)
-	lists             = make(map[string]*model.ListedRecord)
+	// StrictParsingHeight is the first block parsed in strict mode, see parseStrictProtoData.
+	// It is disabled by default.
+	StrictParsingHeight uint64 = math.MaxUint64
+
+	// stateLock guards the indexed state below, HandleNewBlock writes it and the query functions read it
+	stateLock sync.RWMutex
+
+	inscriptionNumber  uint64 = 0
+	inscriptions       []*model.Inscription
+	inscriptionsByHash = make(map[string]*model.Inscription)
+	rrc20Records       []*model.RRC20
+	recordsByHash      = make(map[string][]*model.RRC20)
+	recordsByTick      = make(map[string][]*model.RRC20)
+	recordsByAddress   = make(map[string][]*model.RRC20)
+	tokens             = make(map[string]*model.Token)
+	tokenHolders       = make(map[string]map[string]*model.DDecimal)
+	balances           = make(map[string]map[string]*model.DDecimal)
+	lists              = make(map[string]*model.ListedRecord)
+	tickSkeletons      = make(map[string]string)
 )
 
 var mintLimitWhiteList map[string]bool = map[string]bool{
@@ -36,16 +51,39 @@ var mintLimitWhiteList map[string]bool = map[string]bool{
 func HandleNewBlock(block *model.ChainBlock) error {
 	logrus.Infof("handle block %d", block.Number)
 
+	result, err := applyBlock(block)
+	if err != nil {
+		return err
+	}
+
+	notifyBlockCommitted(result)
+
+	return nil
+}
+
+func applyBlock(block *model.ChainBlock) (*BlockResult, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	start := time.Now()
 	if LatestBlockNumber != block.Number-1 {
 		logrus.Warn("block number not match, latest: ", LatestBlockNumber, ", current: ", block.Number)
-		return errors.New("block number not match")
+		return nil, errors.New("block number not match")
 	}
 
+	pendingResult = &BlockResult{
+		Number:    block.Number,
+		Timestamp: block.Timestamp,
+	}
+	defer func() {
+		pendingResult = nil
+	}()
+
 	for _, trx := range block.Txs {
 		code, err := handleTransaction(trx)
 		if err != nil {
 			if code != 0 {
-				return err
+				return nil, err
 			}
 		}
 	}
@@ -54,47 +92,26 @@ func HandleNewBlock(block *model.ChainBlock) error {
 		code, err := handleReceipt(receipt)
 		if err != nil {
 			if code != 0 {
-				return err
+				return nil, err
 			}
 		}
 	}
 
 	LatestBlockNumber++
+	pendingResult.Root = commitBlock(block)
+	observeBlock(pendingResult, time.Since(start))
 
-	return nil
+	return pendingResult, nil
 }
 
 func handleTransaction(trx *model.ChainTransaction) (int, error) {
-	// data:,
-	if !strings.HasPrefix(trx.Input, "0x646174613a") { //data:
-		return 0, ErrorNoPrefix
-	}
-	logrus.Infof("transaction input start with 0x646174613a, input %s", trx.Input)
-	// trim 0x
-	bytes, err := hex.DecodeString(trx.Input[2:])
+	contentType, content, err := decodeInput(trx)
 	if err != nil {
-		logrus.Warn("inscribe err", err, " at block ", trx.Block, ":", trx.Idx)
-		return 0, ErrorDecode
-	}
-	input := string(bytes)
-
-	sepIdx := strings.Index(input, ",")
-	if sepIdx == -1 || sepIdx == len(input)-1 {
-		return 0, errors.New("no content")
-	}
-	contentType := "text/plain"
-	if sepIdx > 5 {
-		contentType = input[5:sepIdx]
+		return 0, err
 	}
-	content := input[sepIdx+1:]
 
 	newInscriptionNumber := inscriptionNumber
 
-	if !utf8.ValidString(content) {
-		logrus.Infof("content %v is not valid utf8 string", content)
-		return 0, errors.New("content is not valid utf8 string")
-	}
-
 	var inscription model.Inscription
 	inscription.Number = newInscriptionNumber
 	inscription.Hash = trx.Id
@@ -112,28 +129,53 @@ func handleTransaction(trx *model.ChainTransaction) (int, error) {
 		return code, err
 	}
 
+	appendInscription(&inscription)
 	inscriptionNumber++
 
 	return 0, nil
 }
 
+// decodeInput returns the content type and content of a data uri calldata
+func decodeInput(trx *model.ChainTransaction) (string, string, error) {
+	// data:,
+	if !strings.HasPrefix(trx.Input, "0x646174613a") { //data:
+		return "", "", ErrorNoPrefix
+	}
+	logrus.Infof("transaction input start with 0x646174613a, input %s", trx.Input)
+	// trim 0x
+	bytes, err := hex.DecodeString(trx.Input[2:])
+	if err != nil {
+		logrus.Warn("inscribe err", err, " at block ", trx.Block, ":", trx.Idx)
+		return "", "", ErrorDecode
+	}
+	input := string(bytes)
+
+	sepIdx := strings.Index(input, ",")
+	if sepIdx == -1 || sepIdx == len(input)-1 {
+		return "", "", errors.New("no content")
+	}
+	contentType := "text/plain"
+	if sepIdx > 5 {
+		contentType = input[5:sepIdx]
+	}
+	content := input[sepIdx+1:]
+
+	if !utf8.ValidString(content) {
+		logrus.Infof("content %v is not valid utf8 string", content)
+		return "", "", errors.New("content is not valid utf8 string")
+	}
+	return contentType, content, nil
+}
+
 func handleProtocols(inscription *model.Inscription) (int, error) {
+	strict := inscription.Block >= StrictParsingHeight
 	content := strings.TrimSpace(inscription.Content)
 	logrus.Infof("HandleProtocol: %v,content %v ", inscription, content)
-	if content[0] == '{' {
-		var protoData map[string]string
-		var rawProtoData map[string]interface{}
-		err := json.Unmarshal([]byte(content), &rawProtoData)
+	if len(content) > 0 && content[0] == '{' {
+		protoData, parseCode, err := parseContent(inscription.Content, strict)
 		if err != nil {
 			logrus.Info("json parse error: ", err, ", at ", inscription.Number)
 		} else {
-			protoData = make(map[string]string)
-			for k, v := range rawProtoData {
-				if vstr, ok := v.(string); ok {
-					protoData[k] = vstr
-				}
-			}
-
 			value, ok := protoData["p"]
 			if ok && strings.TrimSpace(value) != "" {
 				protocol := strings.ToLower(value)
@@ -154,9 +196,11 @@ func handleProtocols(inscription *model.Inscription) (int, error) {
 
 					logrus.Infof("protocol: %v", protoData)
 					var err error
-					if strings.TrimSpace(rrc20.Tick) == "" {
+					if parseCode != model.ValidCodeOK {
+						rrc20.Valid = parseCode
+					} else if strings.TrimSpace(rrc20.Tick) == "" {
 						rrc20.Valid = -1 // empty tick
-					} else if len(rrc20.Tick) > 18 {
+					} else if tickLength(rrc20.Tick, strict) > 18 {
 						rrc20.Valid = -2 // too long tick
 					} else if rrc20.Operation == model.RRC20OperationDeploy {
 						rrc20.Valid, err = deployToken(&rrc20, inscription, protoData)
@@ -186,7 +230,7 @@ func handleProtocols(inscription *model.Inscription) (int, error) {
 						return -1, err
 					}
 
-					rrc20Records = append(rrc20Records, &rrc20)
+					appendRecord(&rrc20)
 
 					return 0, nil
 				}
@@ -199,27 +243,9 @@ func handleProtocols(inscription *model.Inscription) (int, error) {
 func deployToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[string]string) (model.ValideCode, error) {
 
 	logrus.Infof("HandleProtocol deploy token: %v,inscription %v", params, inscription)
-	value, ok := params["max"]
-	if !ok {
-		return model.ValidCodeWrongMax, nil
-	}
-	max, precision, err1 := model.NewDecimalFromString(value)
-	if err1 != nil {
-		return model.ValideCodeWrongPrecision, nil
-	}
-	value, ok = params["lim"]
-	if !ok {
-		return model.ValidCodeLimitNotExists, nil
-	}
-	limit, _, err2 := model.NewDecimalFromString(value)
-	if err2 != nil {
-		return model.ValidCodeWrongMaxLimit, nil
-	}
-	if max.Sign() <= 0 || limit.Sign() <= 0 {
-		return model.ValidCodeInvalidSign, nil
-	}
-	if max.Cmp(limit) < 0 {
-		return model.ValidCodeOverLimit, nil
+	max, limit, precision, code := model.ParseDeployArgs(params)
+	if code != model.ValidCodeOK {
+		return code, nil
 	}
 
 	rrc20.Max = max
@@ -227,8 +253,14 @@ func deployToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[
 	rrc20.Limit = limit
 
 	rrc20.Tick = strings.TrimSpace(rrc20.Tick)
+	strict := inscription.Block >= StrictParsingHeight
 	lowerTick := strings.ToLower(rrc20.Tick)
 	_, exists := tokens[lowerTick]
+	if strict {
+		// a tick resolving to a deployed token in any form is taken
+		_, exists = lookupTick(rrc20.Tick)
+		lowerTick = model.NormalizeTick(rrc20.Tick)
+	}
 	if exists {
 		return -17, nil
 	}
@@ -247,27 +279,33 @@ func deployToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[
 		DeployHash:    inscription.Hash,
 	}
 
+	// confusable ticks are only flagged in strict mode
+	if other := indexTick(lowerTick); other != "" && strict {
+		token.Confusable = true
+		token.ConfusableWith = tokens[other].Tick
+		logrus.Warnf("deploy token %s is confusable with %s", token.Tick, token.ConfusableWith)
+	}
+
 	// save
 	tokens[lowerTick] = token
 	tokenHolders[lowerTick] = make(map[string]*model.DDecimal)
+	markTokenChanged(lowerTick)
+
+	recordTokenTransition(token, model.TokenStateDeployed, rrc20.Hash)
 
 	return 1, nil
 }
 
 func mintToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[string]string) (model.ValideCode, error) {
 	logrus.Infof("HandleProtocol mint token: %v,inscription %v", params, inscription)
-	value, ok := params["amt"]
-	if !ok {
-		return model.ValidCodeAmountNotExists, nil
-	}
-	amt, precision, err := model.NewDecimalFromString(value)
-	if err != nil {
-		return model.ValidCodeAmountError, nil
+	amt, precision, code := model.ParseAmountArg(params)
+	if code != model.ValidCodeOK {
+		return code, nil
 	}
 
 	rrc20.Amount = amt
 
-	lowerTick := strings.ToLower(rrc20.Tick)
+	lowerTick := tickKey(rrc20.Tick, inscription.Block)
 	token, exists := tokens[lowerTick]
 	if !exists {
 		return model.ValidCodeTokenNotExists, nil
@@ -305,7 +343,7 @@ func mintToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[st
 	// update amount
 	rrc20.Amount = amt
 
-	newHolder, err := addBalance(rrc20.To, lowerTick, amt)
+	newHolder, err := addBalance(rrc20.To, lowerTick, amt, rrc20.Hash)
 	if err != nil {
 		return 0, err
 	}
@@ -326,6 +364,7 @@ func mintToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[st
 
 	if token.Minted.Cmp(token.Max) == 0 {
 		token.CompletedAt = int64(time.Now().Unix())
+		recordTokenTransition(token, model.TokenStateMintedOut, rrc20.Hash)
 	}
 	if newHolder {
 		token.Holders++
@@ -336,17 +375,13 @@ func mintToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[st
 
 func transferToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[string]string) (model.ValideCode, error) {
 	logrus.Infof("Handle Protocol transfer token: %v,inscription %v", params, inscription)
-	value, ok := params["amt"]
-	if !ok {
-		return model.ValidCodeAmountNotExists, nil
-	}
-	amt, precision, err := model.NewDecimalFromString(value)
-	if err != nil {
-		return model.ValidCodeAmountError, nil
+	amt, precision, code := model.ParseAmountArg(params)
+	if code != model.ValidCodeOK {
+		return code, nil
 	}
 
 	// check token
-	lowerTick := strings.ToLower(rrc20.Tick)
+	lowerTick := tickKey(rrc20.Tick, inscription.Block)
 	token, exists := tokens[lowerTick]
 	if !exists {
 		return model.ValidCodeTokenNotExists, nil
@@ -369,7 +404,7 @@ func transferToken(rrc20 *model.RRC20, inscription *model.Inscription, params ma
 	rrc20.Amount = amt
 
 	// From
-	reduceHolder, err := subBalance(rrc20.From, lowerTick, rrc20.Amount)
+	reduceHolder, err := subBalance(rrc20.From, lowerTick, rrc20.Amount, rrc20.Hash)
 	if err != nil {
 		if err.Error() == "insufficient balance" {
 			return model.ValidCodeBalanceNotSatisfied, nil
@@ -378,7 +413,7 @@ func transferToken(rrc20 *model.RRC20, inscription *model.Inscription, params ma
 	}
 
 	// To
-	newHolder, err := addBalance(rrc20.To, lowerTick, rrc20.Amount)
+	newHolder, err := addBalance(rrc20.To, lowerTick, rrc20.Amount, rrc20.Hash)
 	if err != nil {
 		return model.ValidCodeUnknowError, err
 	}
@@ -397,17 +432,13 @@ func transferToken(rrc20 *model.RRC20, inscription *model.Inscription, params ma
 
 func listToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[string]string) (model.ValideCode, error) {
 	logrus.Infof("Handle Protocol list token: %v,inscription %v", params, inscription)
-	value, ok := params["amt"]
-	if !ok {
-		return model.ValidCodeAmountNotExists, nil
-	}
-	amt, precision, err := model.NewDecimalFromString(value)
-	if err != nil {
-		return model.ValidCodeAmountError, nil
+	amt, precision, code := model.ParseAmountArg(params)
+	if code != model.ValidCodeOK {
+		return code, nil
 	}
 
 	// check token
-	lowerTick := strings.ToLower(rrc20.Tick)
+	lowerTick := tickKey(rrc20.Tick, inscription.Block)
 	token, exists := tokens[lowerTick]
 	if !exists {
 		return model.ValidCodeTokenNotExists, nil
@@ -433,7 +464,7 @@ func listToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[st
 	rrc20.Amount = amt
 
 	// sub balance
-	reduceHolder, err := subBalance(rrc20.From, lowerTick, rrc20.Amount)
+	reduceHolder, err := subBalance(rrc20.From, lowerTick, rrc20.Amount, rrc20.Hash)
 	if err != nil {
 		if err.Error() == "insufficient balance" {
 			return -37, nil
@@ -451,6 +482,7 @@ func listToken(rrc20 *model.RRC20, inscription *model.Inscription, params map[st
 		ListedTs:   inscription.Timestamp,
 	}
 	lists[listRec.Hash] = listRec
+	markListChanged(listRec.Hash)
 
 	if reduceHolder {
 		token.Holders--
@@ -494,7 +526,7 @@ func handleRRCListEvent(txHash string, logAddress common.Address, event *model.R
 	listRec, ok := lists[event.Hash()]
 	if ok {
 		// check token
-		lowerTick := strings.ToLower(listRec.Tick)
+		lowerTick := tickKey(listRec.Tick, pendingBlock())
 		token, _ := tokens[lowerTick]
 
 		rrc20.Tick = listRec.Tick
@@ -504,8 +536,13 @@ func handleRRCListEvent(txHash string, logAddress common.Address, event *model.R
 		rrc20.Amount = listRec.Amount
 
 		if listRec.OriginAddr == strings.ToLower(event.From.Hex()) && listRec.ListedTo == strings.ToLower(logAddress.Hex()) {
-			// add balance
-			newHolder, err := addBalance(event.To.Hex(), listRec.Tick, listRec.Amount)
+			// add balance, under the lowercase buyer address from the strict parsing height on,
+			// the checksummed one before
+			buyer := event.To.Hex()
+			if pendingBlock() >= StrictParsingHeight {
+				buyer = strings.ToLower(buyer)
+			}
+			newHolder, err := addBalance(buyer, lowerTick, listRec.Amount, txHash)
 			if err != nil {
 				return model.ValidCodeUnknowError, err
 			}
@@ -517,6 +554,7 @@ func handleRRCListEvent(txHash string, logAddress common.Address, event *model.R
 			}
 
 			delete(lists, listRec.Hash)
+			markListChanged(listRec.Hash)
 		} else {
 			if listRec.OriginAddr == strings.ToLower(event.From.Hex()) {
 				rrc20.Valid = model.ValidCodeListOriginAddressNotMatch
@@ -530,13 +568,12 @@ func handleRRCListEvent(txHash string, logAddress common.Address, event *model.R
 		rrc20.Valid = model.ValidCodeListIdNotExists
 	}
 
-	rrc20Records = append(rrc20Records, &rrc20)
+	appendRecord(&rrc20)
 
 	return model.ValidCodeOK, nil
 }
 
-func subBalance(owner string, tick string, amount *model.DDecimal) (bool, error) {
-	lowerTick := strings.ToLower(tick)
+func subBalance(owner string, lowerTick string, amount *model.DDecimal, hash string) (bool, error) {
 	_, exists := tokens[lowerTick]
 	if !exists {
 		return false, errors.New("token not found")
@@ -561,11 +598,12 @@ func subBalance(owner string, tick string, amount *model.DDecimal) (bool, error)
 	}
 	balances[owner][lowerTick] = fromBalance
 
+	recordBalanceChange(owner, lowerTick, model.NewDecimal().Sub(amount), fromBalance, hash)
+
 	return reduceHolder, nil
 }
 
-func addBalance(owner string, tick string, amount *model.DDecimal) (bool, error) {
-	lowerTick := strings.ToLower(tick)
+func addBalance(owner string, lowerTick string, amount *model.DDecimal, hash string) (bool, error) {
 	_, exists := tokens[lowerTick]
 	if !exists {
 		return false, errors.New("token not found")
@@ -587,5 +625,7 @@ func addBalance(owner string, tick string, amount *model.DDecimal) (bool, error)
 	}
 	balances[owner][lowerTick] = toBalance
 
+	recordBalanceChange(owner, lowerTick, amount, toBalance, hash)
+
 	return newHolder, nil
 }
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+)
+
+// Invariants checked by CheckInvariants
+const (
+	// InvariantSupply is the sum of the balances and the listed amounts equal to Minted
+	InvariantSupply = "supply"
+	// InvariantHolders is Holders equal to the number of non-zero balances
+	InvariantHolders = "holders"
+	// InvariantMax is Minted at most Max
+	InvariantMax = "max"
+)
+
+// InvariantViolation is a token whose counters don't match its balances
+type InvariantViolation struct {
+	Block     uint64
+	Tick      string
+	Invariant string
+	Message   string
+}
+
+func (v *InvariantViolation) Error() string {
+	return fmt.Sprintf("block %d tick %s: %s invariant violated, %s", v.Block, v.Tick, v.Invariant, v.Message)
+}
+
+// CheckInvariants checks the supply and holder accounting of ticks, all tokens if ticks is empty
+func CheckInvariants(ticks []string) []*InvariantViolation {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	lowerTicks := make([]string, 0, len(ticks))
+	if len(ticks) == 0 {
+		for lowerTick := range tokens {
+			lowerTicks = append(lowerTicks, lowerTick)
+		}
+	} else {
+		seen := make(map[string]bool, len(ticks))
+		for _, tick := range ticks {
+			if lowerTick, ok := lookupTick(tick); ok && !seen[lowerTick] {
+				seen[lowerTick] = true
+				lowerTicks = append(lowerTicks, lowerTick)
+			}
+		}
+	}
+	sort.Strings(lowerTicks)
+
+	escrow := make(map[string]*model.DDecimal)
+	for _, listRec := range lists {
+		lowerTick, _ := lookupTick(listRec.Tick)
+		if amount, ok := escrow[lowerTick]; ok {
+			escrow[lowerTick] = amount.Add(listRec.Amount)
+		} else {
+			escrow[lowerTick] = listRec.Amount
+		}
+	}
+
+	var res []*InvariantViolation
+	violate := func(token *model.Token, invariant string, format string, args ...interface{}) {
+		res = append(res, &InvariantViolation{
+			Block:     LatestBlockNumber,
+			Tick:      token.Tick,
+			Invariant: invariant,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+	for _, lowerTick := range lowerTicks {
+		token := tokens[lowerTick]
+		supply := model.NewDecimal()
+		holders := int32(0)
+		for _, balance := range tokenHolders[lowerTick] {
+			if balance.Sign() != 0 {
+				supply = supply.Add(balance)
+				holders++
+			}
+		}
+		listed := model.NewDecimal()
+		if amount, ok := escrow[lowerTick]; ok {
+			listed = amount
+		}
+		if total := supply.Add(listed); total.Cmp(token.Minted) != 0 {
+			violate(token, InvariantSupply, "balances %s plus listed %s is %s, minted %s", supply, listed, total, token.Minted)
+		}
+		if holders != token.Holders {
+			violate(token, InvariantHolders, "%d non-zero balances, holders %d", holders, token.Holders)
+		}
+		if token.Minted.Cmp(token.Max) > 0 {
+			violate(token, InvariantMax, "minted %s is above max %s", token.Minted, token.Max)
+		}
+	}
+	return res
+}
+
+// Ticks returns the ticks the block changed
+func (r *BlockResult) Ticks() []string {
+	var ticks []string
+	for _, rrc20 := range r.Records {
+		if rrc20.Tick != "" {
+			ticks = append(ticks, rrc20.Tick)
+		}
+	}
+	for _, change := range r.BalanceChanges {
+		ticks = append(ticks, change.Tick)
+	}
+	return ticks
+}
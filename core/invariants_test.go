@@ -0,0 +1,74 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	defer ImportState(&State{})
+
+	const (
+		alice = "0x00000000000000000000000000000000000000aa"
+		bob   = "0x00000000000000000000000000000000000000bb"
+		carol = "0x00000000000000000000000000000000000000cc"
+	)
+	listing := func(hash string, tick string, amount string) *model.ListedRecord {
+		return &model.ListedRecord{Hash: hash, Tick: tick, OriginAddr: alice, Amount: decimal(t, amount)}
+	}
+	tests := []struct {
+		name    string
+		minted  string
+		holders int32
+		// balances of alice, bob and carol
+		balances   [3]string
+		lists      []*model.ListedRecord
+		violations []string
+	}{
+		{"no listings", "7", 2, [3]string{"4", "3", "0"}, nil, nil},
+		{"escrowed listing", "10", 2, [3]string{"4", "3", "0"}, []*model.ListedRecord{listing("0x01", "rose", "3")}, nil},
+		{"escrowed listings under another case", "10", 2, [3]string{"4", "3", "0"}, []*model.ListedRecord{listing("0x01", "ROSE", "1"), listing("0x02", "Rose", "2")}, nil},
+		{"listing of another tick", "10", 2, [3]string{"4", "3", "0"}, []*model.ListedRecord{listing("0x01", "gem", "3")}, []string{InvariantSupply}},
+		{"escrow missing", "10", 2, [3]string{"4", "3", "0"}, nil, []string{InvariantSupply}},
+		{"listing kept in the balance", "7", 2, [3]string{"4", "3", "0"}, []*model.ListedRecord{listing("0x01", "rose", "3")}, []string{InvariantSupply}},
+		{"listed out whole balance", "7", 1, [3]string{"0", "3", "0"}, []*model.ListedRecord{listing("0x01", "rose", "4")}, nil},
+		{"holders off", "7", 3, [3]string{"4", "3", "0"}, nil, []string{InvariantHolders}},
+		{"above max", "101", 2, [3]string{"100", "0", "1"}, nil, []string{InvariantMax}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := make(map[string]*model.ListedRecord)
+			for _, listRec := range tt.lists {
+				lists[listRec.Hash] = listRec
+			}
+			ImportState(&State{
+				Version:           StateVersion,
+				LatestBlockNumber: 10,
+				Tokens: map[string]*model.Token{
+					"rose": {Tick: "rose", Max: decimal(t, "100"), Minted: decimal(t, tt.minted), Holders: tt.holders},
+					"gem":  {Tick: "gem", Max: decimal(t, "100"), Minted: decimal(t, "3"), Holders: 0},
+				},
+				TokenHolders: map[string]map[string]*model.DDecimal{
+					"rose": {alice: decimal(t, tt.balances[0]), bob: decimal(t, tt.balances[1]), carol: decimal(t, tt.balances[2])},
+				},
+				Lists: lists,
+			})
+
+			var got []string
+			for _, violation := range CheckInvariants([]string{"Rose"}) {
+				if violation.Tick != "rose" || violation.Block != 10 {
+					t.Fatalf("violation %v", violation)
+				}
+				got = append(got, violation.Invariant)
+			}
+			if len(got) != len(tt.violations) {
+				t.Fatalf("violations = %v, want %v", got, tt.violations)
+			}
+			for i := range got {
+				if got[i] != tt.violations[i] {
+					t.Fatalf("violations = %v, want %v", got, tt.violations)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrorBlockNotIndexed = errors.New("block not indexed yet")
+	ErrorTokenNotFound   = errors.New("token not found")
+
+	// balanceJournal keeps every balance change by tick and owner in block order, guarded by stateLock
+	balanceJournal = make(map[string]map[string][]*model.BalanceChange)
+	// changesByTx and changesByBlock index the journal by lowercase transaction hash and by block
+	changesByTx    = make(map[string][]*model.BalanceChange)
+	changesByBlock = make(map[uint64][]*model.BalanceChange)
+)
+
+// journalBalanceChange appends the change under its owner, keyed like tokenHolders
+func journalBalanceChange(lowerTick string, change *model.BalanceChange) {
+	owners, ok := balanceJournal[lowerTick]
+	if !ok {
+		owners = make(map[string][]*model.BalanceChange)
+		balanceJournal[lowerTick] = owners
+	}
+	owners[change.Address] = append(owners[change.Address], change)
+	hash := strings.ToLower(change.TxHash)
+	changesByTx[hash] = append(changesByTx[hash], change)
+	changesByBlock[change.Block] = append(changesByBlock[change.Block], change)
+}
+
+// balanceAt returns the balance after the last change at or before block, nil if there is none
+func balanceAt(changes []*model.BalanceChange, block uint64) *model.DDecimal {
+	i := sort.Search(len(changes), func(i int) bool {
+		return changes[i].Block > block
+	})
+	if i == 0 {
+		return nil
+	}
+	return changes[i-1].Balance
+}
+
+// QueryBalanceAt returns the balance of address for tick at the end of block
+func QueryBalanceAt(address string, tick string, block uint64) (*model.DDecimal, error) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if block > LatestBlockNumber {
+		return nil, ErrorBlockNotIndexed
+	}
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, ErrorTokenNotFound
+	}
+	balance := balanceAt(balanceJournal[lowerTick][strings.ToLower(address)], block)
+	if balance == nil {
+		return model.NewDecimal(), nil
+	}
+	return balance, nil
+}
+
+// QueryBalancesAt returns the non-zero balances of address at the end of block sorted by tick
+func QueryBalancesAt(address string, block uint64) ([]*Balance, error) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if block > LatestBlockNumber {
+		return nil, ErrorBlockNotIndexed
+	}
+	address = strings.ToLower(address)
+	res := make([]*Balance, 0)
+	for lowerTick, owners := range balanceJournal {
+		if balance := balanceAt(owners[address], block); balance != nil && balance.Sign() != 0 {
+			res = append(res, &Balance{Tick: tokens[lowerTick].Tick, Amount: balance})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Tick < res[j].Tick
+	})
+	return res, nil
+}
+
+// QueryHoldersAt returns the holders of tick at the end of block sorted like QueryHolders,
+// ok is false if the token is unknown
+func QueryHoldersAt(tick string, block uint64, offset int, limit int) ([]*Holder, int, bool, error) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if block > LatestBlockNumber {
+		return nil, 0, false, ErrorBlockNotIndexed
+	}
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, 0, false, nil
+	}
+	all := make([]*Holder, 0)
+	for address, changes := range balanceJournal[lowerTick] {
+		if balance := balanceAt(changes, block); balance != nil && balance.Sign() != 0 {
+			all = append(all, &Holder{Address: address, Balance: balance})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if cmp := all[i].Balance.Cmp(all[j].Balance); cmp != 0 {
+			return cmp > 0
+		}
+		return all[i].Address < all[j].Address
+	})
+
+	start, end := paginate(len(all), offset, limit)
+	return all[start:end], len(all), true, nil
+}
+
+// QueryBalanceHistory returns the balance changes of address for tick in block order
+func QueryBalanceHistory(address string, tick string, offset int, limit int) ([]*model.BalanceChange, int, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, 0, false
+	}
+	changes := balanceJournal[lowerTick][strings.ToLower(address)]
+	start, end := paginate(len(changes), offset, limit)
+	res := make([]*model.BalanceChange, 0, end-start)
+	for _, change := range changes[start:end] {
+		copied := *change
+		res = append(res, &copied)
+	}
+	return res, len(changes), true
+}
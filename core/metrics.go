@@ -0,0 +1,18 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/metrics"
+	"time"
+)
+
+// observeBlock updates the metrics after a block is applied, the caller holds the state lock
+func observeBlock(result *BlockResult, elapsed time.Duration) {
+	metrics.BlockProcessing.Observe(elapsed.Seconds())
+	metrics.SetIndexedHeight(LatestBlockNumber)
+	metrics.Inscriptions.Add(float64(len(result.Inscriptions)))
+	for _, rrc20 := range result.Records {
+		metrics.ObserveOperation(string(rrc20.Operation), int8(rrc20.Valid))
+	}
+	metrics.Tokens.Set(float64(len(tokens)))
+	metrics.ActiveListings.Set(float64(len(lists)))
+}
@@ -0,0 +1,44 @@
+package model
+
+// The argument rules shared by the indexer and the calldata builder
+
+// ParseDeployArgs reads the max and lim of a deploy, the precision of the token is the one of max
+func ParseDeployArgs(params map[string]string) (*DDecimal, *DDecimal, int, ValideCode) {
+	value, ok := params["max"]
+	if !ok {
+		return nil, nil, 0, ValidCodeWrongMax
+	}
+	max, precision, err := NewDecimalFromString(value)
+	if err != nil {
+		return nil, nil, 0, ValideCodeWrongPrecision
+	}
+	value, ok = params["lim"]
+	if !ok {
+		return nil, nil, 0, ValidCodeLimitNotExists
+	}
+	limit, _, err := NewDecimalFromString(value)
+	if err != nil {
+		return nil, nil, 0, ValidCodeWrongMaxLimit
+	}
+	if max.Sign() <= 0 || limit.Sign() <= 0 {
+		return nil, nil, 0, ValidCodeInvalidSign
+	}
+	if max.Cmp(limit) < 0 {
+		return nil, nil, 0, ValidCodeOverLimit
+	}
+	return max, limit, precision, ValidCodeOK
+}
+
+// ParseAmountArg reads the amt of a mint, transfer or list with its precision,
+// the sign is checked by the operations after the token lookup
+func ParseAmountArg(params map[string]string) (*DDecimal, int, ValideCode) {
+	value, ok := params["amt"]
+	if !ok {
+		return nil, 0, ValidCodeAmountNotExists
+	}
+	amt, precision, err := NewDecimalFromString(value)
+	if err != nil {
+		return nil, 0, ValidCodeAmountError
+	}
+	return amt, precision, ValidCodeOK
+}
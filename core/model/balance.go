@@ -0,0 +1,11 @@
+package model
+
+// BalanceChange is one update of an address balance, Delta is negative when the balance decreases
+type BalanceChange struct {
+	Block   uint64
+	TxHash  string
+	Address string
+	Tick    string
+	Delta   *DDecimal
+	Balance *DDecimal
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// maxTickBytes keeps built ticks within the limit in both parsing modes,
+// the legacy one counts bytes and the strict one characters
+const maxTickBytes = 18
+
+// RRC20Call is an rrc-20 operation to inscribe, Max and Limit are used by deploy
+// and Amount by mint, transfer and list
+type RRC20Call struct {
+	Operation RRC20Operation
+	Tick      string
+	Max       string
+	Limit     string
+	Amount    string
+}
+
+// Validate checks the call against the rules that don't depend on the indexed state,
+// with the same codes the indexer records
+func (c *RRC20Call) Validate() ValideCode {
+	tick := strings.TrimSpace(c.Tick)
+	if tick == "" {
+		return ValidCodeEmptyTick
+	}
+	if len(tick) > maxTickBytes {
+		return ValidCodeTooLongTick
+	}
+
+	switch c.Operation {
+	case RRC20OperationDeploy:
+		_, _, _, code := ParseDeployArgs(c.params())
+		return code
+	case RRC20OperationMint, RRC20OperationTransfer, RRC20OperationList:
+		amt, _, code := ParseAmountArg(c.params())
+		if code != ValidCodeOK {
+			return code
+		}
+		if amt.Sign() <= 0 {
+			return ValidCodeInvalidSign
+		}
+		return ValidCodeOK
+	default:
+		return ValideCodeWrongOperation
+	}
+}
+
+func (c *RRC20Call) params() map[string]string {
+	params := make(map[string]string)
+	if c.Operation == RRC20OperationDeploy {
+		params["max"] = c.Max
+		params["lim"] = c.Limit
+	} else {
+		params["amt"] = c.Amount
+	}
+	return params
+}
+
+// DataURI returns the inscription content without whitespace and with keys in a fixed order,
+// so it parses the same in legacy and strict mode
+func (c *RRC20Call) DataURI() string {
+	fields := [][2]string{
+		{"p", RRC20ProtocolName},
+		{"op", string(c.Operation)},
+		{"tick", strings.TrimSpace(c.Tick)},
+	}
+	if c.Operation == RRC20OperationDeploy {
+		fields = append(fields, [2]string{"max", c.Max}, [2]string{"lim", c.Limit})
+	} else {
+		fields = append(fields, [2]string{"amt", c.Amount})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("data:,{")
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(jsonString(field[0]))
+		buf.WriteByte(':')
+		buf.WriteString(jsonString(field[1]))
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Calldata returns the 0x hex transaction input, the code is not ValidCodeOK if the call is invalid
+func (c *RRC20Call) Calldata() (string, ValideCode) {
+	if code := c.Validate(); code != ValidCodeOK {
+		return "", code
+	}
+	return "0x" + hex.EncodeToString([]byte(c.DataURI())), ValidCodeOK
+}
+
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestRRC20CallValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		call RRC20Call
+		code ValideCode
+	}{
+		{"deploy", RRC20Call{Operation: RRC20OperationDeploy, Tick: "rose", Max: "1000", Limit: "10"}, ValidCodeOK},
+		{"deploy limit above max", RRC20Call{Operation: RRC20OperationDeploy, Tick: "rose", Max: "10", Limit: "1000"}, ValidCodeOverLimit},
+		{"deploy without limit", RRC20Call{Operation: RRC20OperationDeploy, Tick: "rose", Max: "10"}, ValidCodeWrongMaxLimit},
+		{"deploy with a bad max", RRC20Call{Operation: RRC20OperationDeploy, Tick: "rose", Max: "1e3", Limit: "10"}, ValideCodeWrongPrecision},
+		{"mint", RRC20Call{Operation: RRC20OperationMint, Tick: "rose", Amount: "1.5"}, ValidCodeOK},
+		{"transfer of nothing", RRC20Call{Operation: RRC20OperationTransfer, Tick: "rose", Amount: "0"}, ValidCodeInvalidSign},
+		{"list of a bad amount", RRC20Call{Operation: RRC20OperationList, Tick: "rose", Amount: "x"}, ValidCodeAmountError},
+		{"blank tick", RRC20Call{Operation: RRC20OperationMint, Tick: "  ", Amount: "1"}, ValidCodeEmptyTick},
+		{"tick of 18 bytes", RRC20Call{Operation: RRC20OperationMint, Tick: strings.Repeat("r", 18), Amount: "1"}, ValidCodeOK},
+		{"tick of 19 bytes", RRC20Call{Operation: RRC20OperationMint, Tick: strings.Repeat("r", 19), Amount: "1"}, ValidCodeTooLongTick},
+		{"exchange", RRC20Call{Operation: RRC20OperationExchange, Tick: "rose", Amount: "1"}, ValideCodeWrongOperation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := tt.call.Validate(); code != tt.code {
+				t.Fatalf("Validate = %d, want %d", code, tt.code)
+			}
+			calldata, code := tt.call.Calldata()
+			if code != tt.code || (code == ValidCodeOK) == (calldata == "") {
+				t.Fatalf("Calldata = %q, %d, want code %d", calldata, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestRRC20CallDataURI(t *testing.T) {
+	tests := []struct {
+		call RRC20Call
+		uri  string
+	}{
+		{RRC20Call{Operation: RRC20OperationDeploy, Tick: " rose ", Max: "1000", Limit: "10"}, `data:,{"p":"rrc-20","op":"deploy","tick":"rose","max":"1000","lim":"10"}`},
+		{RRC20Call{Operation: RRC20OperationMint, Tick: "a<b", Amount: "1"}, `data:,{"p":"rrc-20","op":"mint","tick":"a<b","amt":"1"}`},
+		{RRC20Call{Operation: RRC20OperationTransfer, Tick: `"q"`, Amount: "2"}, `data:,{"p":"rrc-20","op":"transfer","tick":"\"q\"","amt":"2"}`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.call.Operation), func(t *testing.T) {
+			if uri := tt.call.DataURI(); uri != tt.uri {
+				t.Fatalf("DataURI = %s, want %s", uri, tt.uri)
+			}
+			calldata, _ := tt.call.Calldata()
+			if want := "0x" + hex.EncodeToString([]byte(tt.uri)); calldata != want {
+				t.Fatalf("Calldata = %s, want %s", calldata, want)
+			}
+		})
+	}
+}
@@ -14,6 +14,24 @@ type Token struct {
 	CompletedAt   int64
 	DeployAddress string
 	DeployHash    string
+	// Confusable is set when the tick renders like the already deployed ConfusableWith
+	Confusable     bool
+	ConfusableWith string
+}
+
+type TokenState string
+
+const (
+	TokenStateDeployed  TokenState = "deployed"
+	TokenStateMintedOut TokenState = "minted_out"
+)
+
+// TokenTransition records a token entering a new state, minted out is when Progress reaches 1000000
+type TokenTransition struct {
+	Block  uint64
+	TxHash string
+	Tick   string
+	State  TokenState
 }
 
 type ListedRecord struct {
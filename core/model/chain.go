@@ -1,11 +1,14 @@
 package model
 
 import (
+	"rose-scriptions-open-indexer/merkle"
+
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type ChainBlock struct {
 	Number    uint64
+	Hash      string
 	Txs       []*ChainTransaction
 	Receipts  []*ChainReceipt
 	Timestamp uint64
@@ -25,3 +28,13 @@ type ChainReceipt struct {
 	*types.Receipt
 	Timestamp uint64
 }
+
+// BlockRoot is the commitment to the state after a block
+type BlockRoot struct {
+	Number       uint64
+	Hash         string
+	StateRoot    merkle.Hash
+	TokensRoot   merkle.Hash
+	BalancesRoot merkle.Hash
+	ListsRoot    merkle.Hash
+}
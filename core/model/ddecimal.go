@@ -2,6 +2,9 @@ package model
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
 	"rose-scriptions-open-indexer/utils/decimal"
 )
 
@@ -9,6 +12,14 @@ type DDecimal struct {
 	value *decimal.Decimal
 }
 
+type RoundingMode = decimal.RoundingMode
+
+const (
+	RoundDown     = decimal.RoundDown
+	RoundHalfEven = decimal.RoundHalfEven
+	RoundUp       = decimal.RoundUp
+)
+
 func NewDecimal() *DDecimal {
 	return &DDecimal{decimal.New()}
 }
@@ -34,11 +45,50 @@ func (dd *DDecimal) Mul(other *DDecimal) *DDecimal {
 	return &DDecimal{d}
 }
 
+// Quo panics when other is zero, see CheckedQuo
 func (dd *DDecimal) Quo(other *DDecimal) *DDecimal {
 	d := dd.value.Quo(other.value)
 	return &DDecimal{d}
 }
 
+func (dd *DDecimal) MulRound(other *DDecimal, mode RoundingMode) *DDecimal {
+	d := dd.value.MulRound(other.value, mode)
+	return &DDecimal{d}
+}
+
+func (dd *DDecimal) QuoRound(other *DDecimal, mode RoundingMode) *DDecimal {
+	d := dd.value.QuoRound(other.value, mode)
+	return &DDecimal{d}
+}
+
+func (dd *DDecimal) RoundTo(precision int, mode RoundingMode) *DDecimal {
+	d := dd.value.RoundTo(precision, mode)
+	return &DDecimal{d}
+}
+
+func (dd *DDecimal) CheckedAdd(other *DDecimal) (*DDecimal, error) {
+	return wrap(dd.value.CheckedAdd(other.value))
+}
+
+func (dd *DDecimal) CheckedSub(other *DDecimal) (*DDecimal, error) {
+	return wrap(dd.value.CheckedSub(other.value))
+}
+
+func (dd *DDecimal) CheckedMul(other *DDecimal, mode RoundingMode) (*DDecimal, error) {
+	return wrap(dd.value.CheckedMul(other.value, mode))
+}
+
+func (dd *DDecimal) CheckedQuo(other *DDecimal, mode RoundingMode) (*DDecimal, error) {
+	return wrap(dd.value.CheckedQuo(other.value, mode))
+}
+
+func wrap(d *decimal.Decimal, err error) (*DDecimal, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &DDecimal{d}, nil
+}
+
 func (dd *DDecimal) Cmp(other *DDecimal) int {
 	return dd.value.Cmp(other.value)
 }
@@ -55,11 +105,93 @@ func (dd *DDecimal) Float64() float64 {
 	return dd.value.Float64()
 }
 
+func (dd *DDecimal) Rat() *big.Rat {
+	return dd.value.Rat()
+}
+
+func NewDecimalFromScaledInt(value *big.Int, precision int) (*DDecimal, error) {
+	return wrap(decimal.NewFromScaledInt(value, precision))
+}
+
+func (dd *DDecimal) ScaledInt(precision int) (*big.Int, error) {
+	return dd.value.ScaledInt(precision)
+}
+
+func (dd *DDecimal) StringFixed(precision int) string {
+	return dd.value.StringFixed(precision)
+}
+
+func (dd *DDecimal) IsOverflowUint64() bool {
+	return dd.value.IsOverflowUint64()
+}
+
+func (dd *DDecimal) IsOverflowInt64() bool {
+	return dd.value.IsOverflowInt64()
+}
+
+func (dd *DDecimal) IsOverflowScaledUint64(precision int) bool {
+	return dd.value.IsOverflowScaledUint64(precision)
+}
+
+func (dd *DDecimal) IsOverflowScaledInt64(precision int) bool {
+	return dd.value.IsOverflowScaledInt64(precision)
+}
+
+// Scan implements sql.Scanner, NULL scans as zero
 func (dd *DDecimal) Scan(value interface{}) error {
-	str := string(value.([]byte))
+	switch v := value.(type) {
+	case nil:
+		dd.value = decimal.New()
+		return nil
+	case []byte:
+		return dd.parse(string(v))
+	case string:
+		return dd.parse(v)
+	case int64:
+		d, err := decimal.NewFromScaledInt(big.NewInt(v), 0)
+		dd.value = d
+		return err
+	default:
+		return fmt.Errorf("cannot scan %T into DDecimal", value)
+	}
+}
+
+// MarshalJSON encodes the decimal as a json string to keep its precision
+func (dd *DDecimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dd.String())
+}
+
+// UnmarshalJSON accepts a json string or number, null leaves the decimal unchanged
+func (dd *DDecimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+	} else {
+		str = string(data)
+	}
+	return dd.parse(str)
+}
+
+func (dd *DDecimal) MarshalText() ([]byte, error) {
+	return []byte(dd.String()), nil
+}
+
+func (dd *DDecimal) UnmarshalText(text []byte) error {
+	return dd.parse(string(text))
+}
+
+func (dd *DDecimal) parse(str string) error {
 	d, _, err := decimal.NewFromString(str)
+	if err != nil {
+		return err
+	}
 	dd.value = d
-	return err
+	return nil
 }
 
 func (dd *DDecimal) Value() (driver.Value, error) {
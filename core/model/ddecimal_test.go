@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecimalJSON(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err bool
+	}{
+		{`"1.5"`, "1.5", false},
+		{`"0.000000000000000001"`, "0.000000000000000001", false},
+		{`12345678901234567890.5`, "12345678901234567890.5", false},
+		{`"-3"`, "-3", false},
+		{`"1e5"`, "", true},
+		{`""`, "", true},
+		{`true`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			holder := struct{ Amount *DDecimal }{Amount: mustDecimal(t, "7")}
+			err := json.Unmarshal([]byte(`{"Amount":`+tt.in+`}`), &holder)
+			if (err != nil) != tt.err {
+				t.Fatalf("Unmarshal err = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if holder.Amount.String() != tt.out {
+				t.Fatalf("Unmarshal = %s, want %s", holder.Amount, tt.out)
+			}
+			data, err := json.Marshal(holder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `{"Amount":"` + tt.out + `"}`; string(data) != want {
+				t.Fatalf("Marshal = %s, want %s", data, want)
+			}
+		})
+	}
+
+	dd := mustDecimal(t, "7")
+	if err := dd.UnmarshalJSON([]byte("null")); err != nil || dd.String() != "7" {
+		t.Fatalf("UnmarshalJSON(null) = %s, %v, want 7 unchanged", dd, err)
+	}
+}
+
+func TestDecimalTextKeys(t *testing.T) {
+	balances := map[string]*DDecimal{"0xaa": mustDecimal(t, "2.25")}
+	data, err := json.Marshal(balances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]*DDecimal
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["0xaa"].Cmp(balances["0xaa"]) != 0 {
+		t.Fatalf("decoded %s", decoded["0xaa"])
+	}
+
+	dd := NewDecimal()
+	if err := dd.UnmarshalText([]byte("0.5")); err != nil || dd.String() != "0.5" {
+		t.Fatalf("UnmarshalText = %s, %v", dd, err)
+	}
+	if text, _ := dd.MarshalText(); string(text) != "0.5" {
+		t.Fatalf("MarshalText = %s", text)
+	}
+}
+
+func TestDecimalScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		out   string
+		err   bool
+	}{
+		{nil, "0", false},
+		{"1.25", "1.25", false},
+		{[]byte("3"), "3", false},
+		{int64(-4), "-4", false},
+		{"x", "", true},
+		{1.5, "", true},
+	}
+	for _, tt := range tests {
+		dd := NewDecimal()
+		err := dd.Scan(tt.value)
+		if (err != nil) != tt.err || (err == nil && dd.String() != tt.out) {
+			t.Errorf("Scan(%#v) = %s, %v, want %s", tt.value, dd, err, tt.out)
+			continue
+		}
+		if err == nil {
+			if value, _ := dd.Value(); value != tt.out {
+				t.Errorf("Value = %v, want %s", value, tt.out)
+			}
+		}
+	}
+}
+
+func mustDecimal(t *testing.T, s string) *DDecimal {
+	t.Helper()
+	d, _, err := NewDecimalFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
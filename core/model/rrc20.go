@@ -33,6 +33,10 @@ const (
 	ValidCodeTooLongTick     ValideCode = -2
 	ValideCodeWrongOperation ValideCode = -3
 
+	ValidCodeNonStringValue        ValideCode = -4
+	ValidCodeDuplicateKey          ValideCode = -5
+	ValidCodeSurroundingWhitespace ValideCode = -6
+
 	ValidCodeWrongMax        ValideCode = -11
 	ValideCodeWrongPrecision ValideCode = -12
 	ValidCodeLimitNotExists  ValideCode = -13
@@ -80,25 +84,28 @@ type RRC20 struct {
 
 func (code ValideCode) String() string {
 	messages := map[ValideCode]string{
-		ValidCodeUnknowError:         "Unknown error",
-		ValidCodeOK:                  "Operation successful",
-		ValidCodeEmptyTick:           "Empty tick",
-		ValidCodeTooLongTick:         "Tick is too long",
-		ValideCodeWrongOperation:     "Wrong operation",
-		ValidCodeWrongMax:            "Wrong max value",
-		ValideCodeWrongPrecision:     "Wrong precision",
-		ValidCodeLimitNotExists:      "Limit does not exist",
-		ValidCodeWrongMaxLimit:       "Wrong max limit",
-		ValidCodeInvalidSign:         "Invalid sign",
-		ValidCodeOverLimit:           "Over limit",
-		ValidCodeTokenDeployed:       "Token already deployed",
-		ValidCodeAmountNotExists:     "Amount does not exist",
-		ValidCodeAmountError:         "Amount error",
-		ValidCodeTokenNotExists:      "Token does not exist",
-		ValideCodePrecisionNotEqual:  "Precision not equal",
-		ValidCodeOverTotalLimit:      "Over total limit",
-		ValidCodeTransferToSelf:      "Cannot transfer to self",
-		ValidCodeBalanceNotSatisfied: "Balance not satisfied",
+		ValidCodeUnknowError:           "Unknown error",
+		ValidCodeOK:                    "Operation successful",
+		ValidCodeEmptyTick:             "Empty tick",
+		ValidCodeTooLongTick:           "Tick is too long",
+		ValideCodeWrongOperation:       "Wrong operation",
+		ValidCodeNonStringValue:        "Non-string value",
+		ValidCodeDuplicateKey:          "Duplicate key",
+		ValidCodeSurroundingWhitespace: "Whitespace around content",
+		ValidCodeWrongMax:              "Wrong max value",
+		ValideCodeWrongPrecision:       "Wrong precision",
+		ValidCodeLimitNotExists:        "Limit does not exist",
+		ValidCodeWrongMaxLimit:         "Wrong max limit",
+		ValidCodeInvalidSign:           "Invalid sign",
+		ValidCodeOverLimit:             "Over limit",
+		ValidCodeTokenDeployed:         "Token already deployed",
+		ValidCodeAmountNotExists:       "Amount does not exist",
+		ValidCodeAmountError:           "Amount error",
+		ValidCodeTokenNotExists:        "Token does not exist",
+		ValideCodePrecisionNotEqual:    "Precision not equal",
+		ValidCodeOverTotalLimit:        "Over total limit",
+		ValidCodeTransferToSelf:        "Cannot transfer to self",
+		ValidCodeBalanceNotSatisfied:   "Balance not satisfied",
 	}
 
 	msg, ok := messages[code]
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps letters from other scripts onto the latin letter they render like.
+// The table only covers lowercase letters, it is applied after NormalizeTick.
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'г': 'r', 'д': 'g', 'е': 'e', 'ё': 'e', 'з': '3', 'и': 'u', 'к': 'k',
+	'м': 'm', 'н': 'h', 'о': 'o', 'п': 'n', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'ь': 'b', 'ѕ': 's', 'і': 'i', 'ї': 'i', 'ј': 'j', 'һ': 'h', 'ӏ': 'l', 'ԁ': 'd', 'ԛ': 'q',
+	'ԝ': 'w', 'ү': 'y',
+	// greek
+	'α': 'a', 'β': 'b', 'γ': 'y', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// latin look-alikes
+	'ı': 'i', 'ȷ': 'j', 'ɡ': 'g', 'ℓ': 'l',
+}
+
+// NormalizeTick returns the key ticks are compared with: NFKC with full case folding,
+// so "ＲＯＳＥ", "Rose" and "rose" are the same tick.
+func NormalizeTick(tick string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(tick)))
+}
+
+// TickLength counts the tick in grapheme clusters, an emoji with modifiers is one character.
+func TickLength(tick string) int {
+	return uniseg.GraphemeClusterCount(tick)
+}
+
+// TickSkeleton maps a normalized tick to the form it renders like, two ticks with the same
+// skeleton are visually confusable.
+func TickSkeleton(normalizedTick string) string {
+	return strings.Map(func(r rune) rune {
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, normalizedTick)
+}
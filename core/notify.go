@@ -0,0 +1,111 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+)
+
+// BlockResult collects what HandleNewBlock committed for one block
+type BlockResult struct {
+	Number         uint64
+	Timestamp      uint64
+	Inscriptions   []*model.Inscription
+	Records        []*model.RRC20
+	BalanceChanges []*model.BalanceChange
+	Transitions    []*model.TokenTransition
+	// Root is the state commitment after the block
+	Root *model.BlockRoot
+}
+
+var (
+	// pendingResult is the result of the block being applied, nil outside applyBlock
+	pendingResult  *BlockResult
+	blockListeners []func(*BlockResult)
+)
+
+// OnBlockCommitted registers fn to be called after each committed block.
+// Listeners run on the indexing goroutine without the state lock held, they must not block.
+func OnBlockCommitted(fn func(*BlockResult)) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	blockListeners = append(blockListeners, fn)
+}
+
+func notifyBlockCommitted(result *BlockResult) {
+	stateLock.RLock()
+	listeners := blockListeners
+	stateLock.RUnlock()
+
+	for _, fn := range listeners {
+		fn(result)
+	}
+}
+
+func appendInscription(inscription *model.Inscription) {
+	inscriptions = append(inscriptions, inscription)
+	inscriptionsByHash[inscription.Hash] = inscription
+	if pendingResult != nil {
+		pendingResult.Inscriptions = append(pendingResult.Inscriptions, inscription)
+	}
+}
+
+func appendRecord(rrc20 *model.RRC20) {
+	rrc20Records = append(rrc20Records, rrc20)
+	indexRecord(rrc20)
+	if pendingResult != nil {
+		pendingResult.Records = append(pendingResult.Records, rrc20)
+	}
+}
+
+// indexRecord adds the record to the indexes by lowercase hash, normalized tick and lowercase address,
+// each in indexing order
+func indexRecord(rrc20 *model.RRC20) {
+	hash := strings.ToLower(rrc20.Hash)
+	recordsByHash[hash] = append(recordsByHash[hash], rrc20)
+	normalized := model.NormalizeTick(rrc20.Tick)
+	recordsByTick[normalized] = append(recordsByTick[normalized], rrc20)
+	from, to := strings.ToLower(rrc20.From), strings.ToLower(rrc20.To)
+	recordsByAddress[from] = append(recordsByAddress[from], rrc20)
+	if to != from {
+		recordsByAddress[to] = append(recordsByAddress[to], rrc20)
+	}
+}
+
+// pendingBlock returns the number of the block being handled
+func pendingBlock() uint64 {
+	if pendingResult != nil {
+		return pendingResult.Number
+	}
+	return LatestBlockNumber + 1
+}
+
+// recordBalanceChange journals the change and adds it to the pending result
+func recordBalanceChange(owner string, lowerTick string, delta *model.DDecimal, balance *model.DDecimal, hash string) {
+	change := &model.BalanceChange{
+		Block:   pendingBlock(),
+		TxHash:  hash,
+		Address: owner,
+		Tick:    tokens[lowerTick].Tick,
+		Delta:   delta,
+		Balance: balance,
+	}
+	journalBalanceChange(lowerTick, change)
+	markTickDirty(lowerTick)
+	markBalanceChanged(lowerTick, change.Address, change)
+	if pendingResult != nil {
+		pendingResult.BalanceChanges = append(pendingResult.BalanceChanges, change)
+	}
+}
+
+func recordTokenTransition(token *model.Token, state model.TokenState, hash string) {
+	if pendingResult == nil {
+		return
+	}
+	pendingResult.Transitions = append(pendingResult.Transitions, &model.TokenTransition{
+		Block:  pendingResult.Number,
+		TxHash: hash,
+		Tick:   token.Tick,
+		State:  state,
+	})
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+)
+
+var (
+	ErrorNotJsonObject = errors.New("content is not a json object")
+)
+
+// parseProtoData is the legacy parser: non-string values are dropped and the last
+// duplicate key wins.
+func parseProtoData(content string) (map[string]string, model.ValideCode, error) {
+	var rawProtoData map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &rawProtoData); err != nil {
+		return nil, model.ValidCodeUnknowError, err
+	}
+
+	protoData := make(map[string]string)
+	for k, v := range rawProtoData {
+		if vstr, ok := v.(string); ok {
+			protoData[k] = vstr
+		}
+	}
+	return protoData, model.ValidCodeOK, nil
+}
+
+// parseStrictProtoData parses the untrimmed inscription content in strict mode.
+// The fields are extracted exactly as parseProtoData does, but ambiguous payloads are
+// reported with one code. When several apply, the first in this list wins, wherever the
+// offending keys are in the payload:
+//
+//   - ValidCodeSurroundingWhitespace if the content has leading or trailing whitespace
+//   - ValidCodeDuplicateKey if the object contains the same key more than once
+//   - ValidCodeNonStringValue if any value is not a json string
+//
+// An error is returned only when the content is not a single json object.
+func parseStrictProtoData(content string) (map[string]string, model.ValideCode, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	if token, err := decoder.Token(); err != nil {
+		return nil, model.ValidCodeUnknowError, err
+	} else if token != json.Delim('{') {
+		return nil, model.ValidCodeUnknowError, ErrorNotJsonObject
+	}
+
+	protoData := make(map[string]string)
+	seen := make(map[string]bool)
+	duplicateKey, nonString := false, false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, model.ValidCodeUnknowError, err
+		}
+		key := token.(string)
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, model.ValidCodeUnknowError, err
+		}
+
+		if seen[key] {
+			duplicateKey = true
+		}
+		seen[key] = true
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			nonString = true
+			delete(protoData, key)
+			continue
+		}
+		protoData[key] = value
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, model.ValidCodeUnknowError, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, model.ValidCodeUnknowError, ErrorNotJsonObject
+	}
+
+	code := model.ValidCodeOK
+	switch {
+	case content != strings.TrimSpace(content):
+		code = model.ValidCodeSurroundingWhitespace
+	case duplicateKey:
+		code = model.ValidCodeDuplicateKey
+	case nonString:
+		code = model.ValidCodeNonStringValue
+	}
+	return protoData, code, nil
+}
+
+// tickLength counts bytes for legacy blocks and grapheme clusters in strict mode.
+func tickLength(tick string, strict bool) int {
+	if strict {
+		return model.TickLength(tick)
+	}
+	return len(tick)
+}
+
+// parseContent parses inscription content starting with '{', strict mode reads the raw content
+// and the legacy mode the trimmed one
+func parseContent(content string, strict bool) (map[string]string, model.ValideCode, error) {
+	if strict {
+		return parseStrictProtoData(content)
+	}
+	return parseProtoData(strings.TrimSpace(content))
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestParseStrictProtoData(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		code    model.ValideCode
+		fields  map[string]string
+		err     bool
+	}{
+		{"valid", `{"p":"rrc-20","op":"mint","tick":"rose","amt":"1"}`, model.ValidCodeOK, map[string]string{"p": "rrc-20", "op": "mint", "tick": "rose", "amt": "1"}, false},
+		{"leading whitespace", ` {"p":"rrc-20"}`, model.ValidCodeSurroundingWhitespace, map[string]string{"p": "rrc-20"}, false},
+		{"trailing newline", "{\"p\":\"rrc-20\"}\n", model.ValidCodeSurroundingWhitespace, map[string]string{"p": "rrc-20"}, false},
+		{"duplicate key", `{"amt":"1","amt":"2"}`, model.ValidCodeDuplicateKey, map[string]string{"amt": "2"}, false},
+		{"non-string value", `{"amt":1}`, model.ValidCodeNonStringValue, map[string]string{}, false},
+		{"non-string after string drops it", `{"amt":"1","amt":1}`, model.ValidCodeDuplicateKey, map[string]string{}, false},
+		{"duplicate after non-string", `{"max":1,"amt":"1","amt":"2"}`, model.ValidCodeDuplicateKey, map[string]string{"amt": "2"}, false},
+		{"non-string after duplicate", `{"amt":"1","amt":"2","max":1}`, model.ValidCodeDuplicateKey, map[string]string{"amt": "2"}, false},
+		{"whitespace wins over duplicate", ` {"max":1,"amt":"1","amt":"2"}`, model.ValidCodeSurroundingWhitespace, map[string]string{"amt": "2"}, false},
+		{"not an object", `["p"]`, model.ValidCodeUnknowError, nil, true},
+		{"trailing data", `{"p":"rrc-20"}{}`, model.ValidCodeUnknowError, nil, true},
+		{"invalid json", `{"p":}`, model.ValidCodeUnknowError, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, code, err := parseStrictProtoData(tt.content)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if code != tt.code {
+				t.Fatalf("code = %d, want %d", code, tt.code)
+			}
+			if tt.err {
+				return
+			}
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("fields = %v, want %v", fields, tt.fields)
+			}
+			for key, value := range tt.fields {
+				if fields[key] != value {
+					t.Fatalf("fields = %v, want %v", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestParseContentLegacy(t *testing.T) {
+	// legacy mode trims the content, drops non-string values and keeps the last duplicate
+	fields, code, err := parseContent(` {"amt":"1","amt":"2","max":1} `, false)
+	if err != nil || code != model.ValidCodeOK {
+		t.Fatalf("code = %d, err = %v", code, err)
+	}
+	if len(fields) != 1 || fields["amt"] != "2" {
+		t.Fatalf("fields = %v", fields)
+	}
+}
+
+func TestCalldataParsesInBothModes(t *testing.T) {
+	calls := []*model.RRC20Call{
+		{Operation: model.RRC20OperationDeploy, Tick: "Rose", Max: "21000000", Limit: "1000"},
+		{Operation: model.RRC20OperationMint, Tick: "ＲＯＳＥ", Amount: "1000"},
+		{Operation: model.RRC20OperationList, Tick: `ro"se`, Amount: "0.5"},
+	}
+	for _, call := range calls {
+		calldata, code := call.Calldata()
+		if code != model.ValidCodeOK {
+			t.Fatalf("%s: code %d", call.Operation, code)
+		}
+		_, content, err := decodeInput(&model.ChainTransaction{Input: calldata})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, strict := range []bool{false, true} {
+			fields, code, err := parseContent(content, strict)
+			if err != nil || code != model.ValidCodeOK {
+				t.Fatalf("%s strict %v: code %d, err %v", call.Operation, strict, code, err)
+			}
+			if fields["op"] != string(call.Operation) || fields["tick"] != call.Tick {
+				t.Fatalf("%s strict %v: fields %v", call.Operation, strict, fields)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"errors"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrorStateRootNotFound = errors.New("no state root for block")
+	ErrorBalanceNotFound   = errors.New("no balance at block")
+)
+
+// QueryBalanceProof returns the inclusion proof of the balance of address for tick at the end of block.
+// Only non-zero balances are in the state tree, a zero balance has no proof.
+func QueryBalanceProof(address string, tick string, block uint64) (*merkle.BalanceProof, error) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if block > LatestBlockNumber {
+		return nil, ErrorBlockNotIndexed
+	}
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, ErrorTokenNotFound
+	}
+	i := blockRootIndex(block)
+	if i < 0 {
+		return nil, ErrorStateRootNotFound
+	}
+	blockRoot := blockRoots[i]
+
+	holders := lowerHolders(holdersAt(lowerTick, block))
+	owners := balanceOwners(holders)
+	owner := strings.ToLower(address)
+	index := sort.SearchStrings(owners, owner)
+	if index == len(owners) || owners[index] != owner {
+		return nil, ErrorBalanceNotFound
+	}
+
+	roots := tickRootsAt(block)
+	lowerTicks, leaves := tickLeaves(roots)
+	if merkle.Root(leaves) != blockRoot.BalancesRoot {
+		return nil, errors.New("balances do not match the state root of the block")
+	}
+	return &merkle.BalanceProof{
+		Block:       block,
+		Tick:        lowerTick,
+		Address:     owner,
+		Balance:     holders[owner].String(),
+		BalancePath: merkle.Prove(balanceLeaves(holders), index),
+		TickPath:    merkle.Prove(leaves, sort.SearchStrings(lowerTicks, lowerTick)),
+		TokensRoot:  blockRoot.TokensRoot,
+		ListsRoot:   blockRoot.ListsRoot,
+		StateRoot:   blockRoot.StateRoot,
+	}, nil
+}
+
+// holdersAt returns the balances of tick at the end of block
+func holdersAt(lowerTick string, block uint64) map[string]*model.DDecimal {
+	if block == LatestBlockNumber {
+		return tokenHolders[lowerTick]
+	}
+	holders := make(map[string]*model.DDecimal)
+	for owner, changes := range balanceJournal[lowerTick] {
+		if balance := balanceAt(changes, block); balance != nil {
+			holders[owner] = balance
+		}
+	}
+	return holders
+}
+
+// historicalRootsSize is the number of blocks whose tick roots are kept for proofs
+const historicalRootsSize = 32
+
+// historicalRoots caches the tick roots of past blocks, rebuilding them replays the whole journal.
+// It has its own lock since proofs only hold stateLock for reading, rebuilds run one at a time.
+var historicalRoots = &tickRootsCache{roots: make(map[uint64]map[string]merkle.Hash)}
+
+type tickRootsCache struct {
+	lock sync.Mutex
+	// blocks are the cached blocks, least recently used first
+	blocks []uint64
+	roots  map[uint64]map[string]merkle.Hash
+}
+
+func (c *tickRootsCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.blocks = nil
+	c.roots = make(map[uint64]map[string]merkle.Hash)
+}
+
+// get returns the tick roots of block, building and caching them if needed. The roots are not copied.
+func (c *tickRootsCache) get(block uint64, build func() map[string]merkle.Hash) map[string]merkle.Hash {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	roots, ok := c.roots[block]
+	if !ok {
+		roots = build()
+		c.roots[block] = roots
+	}
+	for i, cached := range c.blocks {
+		if cached == block {
+			c.blocks = append(c.blocks[:i], c.blocks[i+1:]...)
+			break
+		}
+	}
+	c.blocks = append(c.blocks, block)
+	if len(c.blocks) > historicalRootsSize {
+		delete(c.roots, c.blocks[0])
+		c.blocks = c.blocks[1:]
+	}
+	return roots
+}
+
+// tickRootsAt returns the balance tree roots of the ticks with holders at the end of block.
+// The roots of past blocks are cached, the latest ones start from tickRoots which is only read,
+// the lock may be shared.
+func tickRootsAt(block uint64) map[string]merkle.Hash {
+	if block == LatestBlockNumber {
+		roots := make(map[string]merkle.Hash)
+		for lowerTick, root := range tickRoots {
+			if !dirtyTicks[lowerTick] {
+				roots[lowerTick] = root
+			}
+		}
+		return buildTickRoots(roots, block)
+	}
+	return historicalRoots.get(block, func() map[string]merkle.Hash {
+		return buildTickRoots(make(map[string]merkle.Hash), block)
+	})
+}
+
+// buildTickRoots adds the roots of the ticks missing from roots, rebuilt from the journal
+func buildTickRoots(roots map[string]merkle.Hash, block uint64) map[string]merkle.Hash {
+	for lowerTick := range balanceJournal {
+		if _, ok := roots[lowerTick]; ok {
+			continue
+		}
+		if root := merkle.Root(balanceLeaves(holdersAt(lowerTick, block))); root != merkle.EmptyRoot {
+			roots[lowerTick] = root
+		}
+	}
+	return roots
+}
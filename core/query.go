@@ -0,0 +1,342 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strings"
+)
+
+// The query functions read the indexed state under stateLock and return copies,
+// so the results stay consistent while new blocks are handled.
+
+type Holder struct {
+	Address string
+	Balance *model.DDecimal
+}
+
+type Balance struct {
+	Tick   string
+	Amount *model.DDecimal
+}
+
+type RecordFilter struct {
+	Tick      string
+	Address   string
+	Operation model.RRC20Operation
+	Valid     *model.ValideCode
+}
+
+func (f *RecordFilter) match(rrc20 *model.RRC20) bool {
+	if f.Tick != "" && !sameTick(rrc20.Tick, f.Tick) {
+		return false
+	}
+	if f.Address != "" && !strings.EqualFold(rrc20.From, f.Address) && !strings.EqualFold(rrc20.To, f.Address) {
+		return false
+	}
+	if f.Operation != "" && rrc20.Operation != f.Operation {
+		return false
+	}
+	if f.Valid != nil && rrc20.Valid != *f.Valid {
+		return false
+	}
+	return true
+}
+
+type ListingFilter struct {
+	Tick    string
+	Address string
+}
+
+func (f *ListingFilter) match(listRec *model.ListedRecord) bool {
+	if f.Tick != "" && !sameTick(listRec.Tick, f.Tick) {
+		return false
+	}
+	if f.Address != "" && !strings.EqualFold(listRec.OriginAddr, f.Address) && !strings.EqualFold(listRec.ListedTo, f.Address) {
+		return false
+	}
+	return true
+}
+
+// paginate returns the bounds of the page starting at offset
+func paginate(total int, offset int, limit int) (int, int) {
+	if offset < 0 || offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if limit < 0 || end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func QueryLatestBlockNumber() uint64 {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	return LatestBlockNumber
+}
+
+// QueryTokens returns the tokens in deploy order and the total count
+func QueryTokens(offset int, limit int) ([]*model.Token, int) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	all := make([]*model.Token, 0, len(tokens))
+	for _, token := range tokens {
+		all = append(all, token)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Number < all[j].Number
+	})
+
+	start, end := paginate(len(all), offset, limit)
+	res := make([]*model.Token, 0, end-start)
+	for _, token := range all[start:end] {
+		copied := *token
+		res = append(res, &copied)
+	}
+	return res, len(all)
+}
+
+func QueryToken(tick string) (*model.Token, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, false
+	}
+	token := tokens[lowerTick]
+	copied := *token
+	return &copied, true
+}
+
+// QueryHolders returns the holders with a non-zero balance of tick, sorted by balance descending
+func QueryHolders(tick string, offset int, limit int) ([]*Holder, int, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	lowerTick, ok := lookupTick(tick)
+	if !ok {
+		return nil, 0, false
+	}
+	holders, ok := tokenHolders[lowerTick]
+	if !ok {
+		return nil, 0, false
+	}
+	all := make([]*Holder, 0, len(holders))
+	for address, balance := range holders {
+		if balance.Sign() != 0 {
+			all = append(all, &Holder{Address: address, Balance: balance})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if cmp := all[i].Balance.Cmp(all[j].Balance); cmp != 0 {
+			return cmp > 0
+		}
+		return all[i].Address < all[j].Address
+	})
+
+	start, end := paginate(len(all), offset, limit)
+	return all[start:end], len(all), true
+}
+
+// QueryBalances returns the non-zero balances of address sorted by tick
+func QueryBalances(address string) []*Balance {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	res := make([]*Balance, 0)
+	for tick, amount := range balances[strings.ToLower(address)] {
+		if amount.Sign() != 0 {
+			res = append(res, &Balance{Tick: tokens[tick].Tick, Amount: amount})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Tick < res[j].Tick
+	})
+	return res
+}
+
+// QueryRecords returns the rrc-20 operations matching filter in indexing order.
+// With an address or tick, only the records indexed under it are matched.
+func QueryRecords(filter RecordFilter, offset int, limit int) ([]*model.RRC20, int) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	candidates := rrc20Records
+	if filter.Address != "" {
+		candidates = recordsByAddress[strings.ToLower(filter.Address)]
+	}
+	if filter.Tick != "" {
+		// ticks referring to the same token normalize alike, the normalized tick narrows them down
+		byTick := recordsByTick[model.NormalizeTick(filter.Tick)]
+		if filter.Address == "" || len(byTick) < len(candidates) {
+			candidates = byTick
+		}
+	}
+	var all []*model.RRC20
+	for _, rrc20 := range candidates {
+		if filter.match(rrc20) {
+			all = append(all, rrc20)
+		}
+	}
+
+	start, end := paginate(len(all), offset, limit)
+	res := make([]*model.RRC20, 0, end-start)
+	for _, rrc20 := range all[start:end] {
+		copied := *rrc20
+		res = append(res, &copied)
+	}
+	return res, len(all)
+}
+
+func QueryInscriptionByHash(hash string) (*model.Inscription, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	inscription, ok := inscriptionsByHash[strings.ToLower(hash)]
+	if !ok {
+		return nil, false
+	}
+	copied := *inscription
+	return &copied, true
+}
+
+func QueryInscriptionByNumber(number uint64) (*model.Inscription, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if number >= uint64(len(inscriptions)) {
+		return nil, false
+	}
+	copied := *inscriptions[number]
+	return &copied, true
+}
+
+// QueryListings returns the open listings matching filter, oldest first
+func QueryListings(filter ListingFilter, offset int, limit int) ([]*model.ListedRecord, int) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	var all []*model.ListedRecord
+	for _, listRec := range lists {
+		if filter.match(listRec) {
+			all = append(all, listRec)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].ListedTs != all[j].ListedTs {
+			return all[i].ListedTs < all[j].ListedTs
+		}
+		return all[i].Hash < all[j].Hash
+	})
+
+	start, end := paginate(len(all), offset, limit)
+	res := make([]*model.ListedRecord, 0, end-start)
+	for _, listRec := range all[start:end] {
+		copied := *listRec
+		res = append(res, &copied)
+	}
+	return res, len(all)
+}
+
+// QueryRecordByHash returns the first rrc-20 operation of a transaction
+func QueryRecordByHash(hash string) (*model.RRC20, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	records, ok := recordsByHash[strings.ToLower(hash)]
+	if !ok {
+		return nil, false
+	}
+	copied := *records[0]
+	return &copied, true
+}
+
+// Transaction is everything indexed for one transaction hash
+type Transaction struct {
+	Hash           string
+	Inscription    *model.Inscription `json:",omitempty"`
+	Records        []*model.RRC20
+	BalanceChanges []*model.BalanceChange
+}
+
+// QueryTransaction returns the inscription, rrc-20 operations and balance changes of a transaction
+func QueryTransaction(hash string) (*Transaction, bool) {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	tx := queryTransaction(strings.ToLower(hash))
+	if tx.Inscription == nil && len(tx.Records) == 0 && len(tx.BalanceChanges) == 0 {
+		return nil, false
+	}
+	return tx, true
+}
+
+// QueryBlockTransactions returns the indexed transactions of a block: its inscriptions
+// followed by the other transactions that changed balances, such as settlements.
+func QueryBlockTransactions(number uint64) []*Transaction {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	var hashes []string
+	seen := make(map[string]bool)
+	add := func(hash string) {
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	i := sort.Search(len(inscriptions), func(i int) bool {
+		return inscriptions[i].Block >= number
+	})
+	for ; i < len(inscriptions) && inscriptions[i].Block == number; i++ {
+		add(inscriptions[i].Hash)
+	}
+	var changes []*model.BalanceChange
+	for _, change := range changesByBlock[number] {
+		if !seen[strings.ToLower(change.TxHash)] {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].TxHash < changes[j].TxHash
+	})
+	for _, change := range changes {
+		add(strings.ToLower(change.TxHash))
+	}
+
+	res := make([]*Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		res = append(res, queryTransaction(hash))
+	}
+	return res
+}
+
+func queryTransaction(hash string) *Transaction {
+	tx := &Transaction{
+		Hash:           hash,
+		Records:        make([]*model.RRC20, 0),
+		BalanceChanges: make([]*model.BalanceChange, 0),
+	}
+	if inscription, ok := inscriptionsByHash[hash]; ok {
+		copied := *inscription
+		tx.Inscription = &copied
+	}
+	for _, rrc20 := range recordsByHash[hash] {
+		copied := *rrc20
+		tx.Records = append(tx.Records, &copied)
+	}
+	for _, change := range changesByTx[hash] {
+		copied := *change
+		tx.BalanceChanges = append(tx.BalanceChanges, &copied)
+	}
+	sort.Slice(tx.BalanceChanges, func(i, j int) bool {
+		if tx.BalanceChanges[i].Tick != tx.BalanceChanges[j].Tick {
+			return tx.BalanceChanges[i].Tick < tx.BalanceChanges[j].Tick
+		}
+		return tx.BalanceChanges[i].Address < tx.BalanceChanges[j].Address
+	})
+	return tx
+}
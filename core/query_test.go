@@ -0,0 +1,63 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestQueryIndexes(t *testing.T) {
+	defer ImportState(&State{})
+
+	ImportState(&State{
+		Version:           StateVersion,
+		LatestBlockNumber: 6,
+		Tokens:            map[string]*model.Token{"rose": {Tick: "rose"}},
+		Records: []*model.RRC20{
+			{Hash: "0xA1", Tick: "ROSE", From: "0xaa", To: "0xBB", Operation: model.RRC20OperationTransfer},
+			{Hash: "0xa2", Tick: "gem", From: "0xbb", To: "0xbb", Operation: model.RRC20OperationMint},
+		},
+		BalanceChanges: []*model.BalanceChange{
+			{Block: 5, TxHash: "0xa1", Address: "0xaa", Tick: "rose"},
+			{Block: 5, TxHash: "0xa1", Address: "0xbb", Tick: "rose"},
+			{Block: 6, TxHash: "0xc3", Address: "0xbb", Tick: "rose"},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		filter RecordFilter
+		hashes []string
+	}{
+		{"all", RecordFilter{}, []string{"0xA1", "0xa2"}},
+		{"tick", RecordFilter{Tick: "Rose"}, []string{"0xA1"}},
+		{"address", RecordFilter{Address: "0xBB"}, []string{"0xA1", "0xa2"}},
+		{"address and tick", RecordFilter{Address: "0xbb", Tick: "gem"}, []string{"0xa2"}},
+		{"operation", RecordFilter{Address: "0xbb", Operation: model.RRC20OperationMint}, []string{"0xa2"}},
+		{"unknown tick", RecordFilter{Tick: "none"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, total := QueryRecords(tt.filter, 0, -1)
+			if total != len(tt.hashes) {
+				t.Fatalf("total = %d, want %d", total, len(tt.hashes))
+			}
+			for i, rrc20 := range records {
+				if rrc20.Hash != tt.hashes[i] {
+					t.Fatalf("record %d = %s, want %s", i, rrc20.Hash, tt.hashes[i])
+				}
+			}
+		})
+	}
+
+	tx, ok := QueryTransaction("0xA1")
+	if !ok || len(tx.Records) != 1 || len(tx.BalanceChanges) != 2 {
+		t.Fatalf("transaction = %+v, %v", tx, ok)
+	}
+	if rrc20, ok := QueryRecordByHash("0xA2"); !ok || rrc20.Tick != "gem" {
+		t.Fatalf("record = %+v, %v", rrc20, ok)
+	}
+	txs := QueryBlockTransactions(6)
+	if len(txs) != 1 || txs[0].Hash != "0xc3" || len(txs[0].BalanceChanges) != 1 {
+		t.Fatalf("block transactions = %+v", txs)
+	}
+}
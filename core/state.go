@@ -0,0 +1,165 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+)
+
+// StateVersion is bumped when the State layout changes
+const StateVersion = 2
+
+// State is the whole indexed state at LatestBlockNumber, as saved by the storage layer.
+// The lookup indexes are not part of it, ImportState rebuilds them.
+type State struct {
+	Version           int
+	LatestBlockNumber uint64
+	InscriptionNumber uint64
+	Inscriptions      []*model.Inscription
+	Records           []*model.RRC20
+	Tokens            map[string]*model.Token
+	TokenHolders      map[string]map[string]*model.DDecimal
+	Balances          map[string]map[string]*model.DDecimal
+	Lists             map[string]*model.ListedRecord
+	// BalanceChanges is the balance journal, in block order for each address and tick
+	BalanceChanges []*model.BalanceChange
+	// BlockRoots are the state commitments in block order, since version 2
+	BlockRoots []*model.BlockRoot
+	// ImportedTo is the block of the last snapshot loaded in place of indexing,
+	// the state roots up to it were not computed by this indexer
+	ImportedTo uint64
+}
+
+// importedTo is the ImportedTo of the state, guarded by stateLock
+var importedTo uint64
+
+// ExportState returns the current state. It shares the indexed objects, so it must be
+// serialized before the next HandleNewBlock, i.e. on the indexing goroutine.
+func ExportState() *State {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	state := &State{
+		Version:           StateVersion,
+		LatestBlockNumber: LatestBlockNumber,
+		InscriptionNumber: inscriptionNumber,
+		Inscriptions:      append([]*model.Inscription(nil), inscriptions...),
+		Records:           append([]*model.RRC20(nil), rrc20Records...),
+		Tokens:            make(map[string]*model.Token, len(tokens)),
+		TokenHolders:      make(map[string]map[string]*model.DDecimal, len(tokenHolders)),
+		Balances:          make(map[string]map[string]*model.DDecimal, len(balances)),
+		Lists:             make(map[string]*model.ListedRecord, len(lists)),
+		BlockRoots:        append([]*model.BlockRoot(nil), blockRoots...),
+		ImportedTo:        importedTo,
+	}
+	for lowerTick, token := range tokens {
+		state.Tokens[lowerTick] = token
+	}
+	for lowerTick, holders := range tokenHolders {
+		state.TokenHolders[lowerTick] = copyBalances(holders)
+	}
+	for owner, ownerBalances := range balances {
+		state.Balances[owner] = copyBalances(ownerBalances)
+	}
+	for hash, listRec := range lists {
+		state.Lists[hash] = listRec
+	}
+
+	lowerTicks := make([]string, 0, len(balanceJournal))
+	for lowerTick := range balanceJournal {
+		lowerTicks = append(lowerTicks, lowerTick)
+	}
+	sort.Strings(lowerTicks)
+	for _, lowerTick := range lowerTicks {
+		owners := make([]string, 0, len(balanceJournal[lowerTick]))
+		for owner := range balanceJournal[lowerTick] {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+		for _, owner := range owners {
+			state.BalanceChanges = append(state.BalanceChanges, balanceJournal[lowerTick][owner]...)
+		}
+	}
+	return state
+}
+
+// ImportState replaces the indexed state and rebuilds the lookup indexes
+func ImportState(state *State) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	LatestBlockNumber = state.LatestBlockNumber
+	inscriptionNumber = state.InscriptionNumber
+	inscriptions = state.Inscriptions
+	rrc20Records = state.Records
+	tokens = state.Tokens
+	tokenHolders = state.TokenHolders
+	balances = state.Balances
+	lists = state.Lists
+	if tokens == nil {
+		tokens = make(map[string]*model.Token)
+	}
+	if tokenHolders == nil {
+		tokenHolders = make(map[string]map[string]*model.DDecimal)
+	}
+	if balances == nil {
+		balances = make(map[string]map[string]*model.DDecimal)
+	}
+	if lists == nil {
+		lists = make(map[string]*model.ListedRecord)
+	}
+
+	inscriptionsByHash = make(map[string]*model.Inscription, len(inscriptions))
+	for _, inscription := range inscriptions {
+		inscriptionsByHash[inscription.Hash] = inscription
+	}
+	recordsByHash = make(map[string][]*model.RRC20, len(rrc20Records))
+	recordsByTick = make(map[string][]*model.RRC20)
+	recordsByAddress = make(map[string][]*model.RRC20)
+	for _, rrc20 := range rrc20Records {
+		indexRecord(rrc20)
+	}
+
+	// the first deployed tick of a skeleton is the one later ticks are confusable with
+	ordered := make([]string, 0, len(tokens))
+	for lowerTick := range tokens {
+		ordered = append(ordered, lowerTick)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return tokens[ordered[i]].Number < tokens[ordered[j]].Number
+	})
+	normalizedTicks = make(map[string]string, len(tokens))
+	tickSkeletons = make(map[string]string, len(tokens))
+	for _, lowerTick := range ordered {
+		indexTick(lowerTick)
+	}
+
+	// journal entries carry the deployed tick, it resolves to the key of its token
+	balanceJournal = make(map[string]map[string][]*model.BalanceChange)
+	changesByTx = make(map[string][]*model.BalanceChange)
+	changesByBlock = make(map[uint64][]*model.BalanceChange)
+	for _, change := range state.BalanceChanges {
+		lowerTick, _ := lookupTick(change.Tick)
+		journalBalanceChange(lowerTick, change)
+	}
+
+	blockRoots = state.BlockRoots
+	importedTo = state.ImportedTo
+	resetTickRoots()
+	resetChanges(false)
+}
+
+// QueryImportedTo returns the block of the last snapshot loaded, 0 if every block was indexed here
+func QueryImportedTo() uint64 {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	return importedTo
+}
+
+func copyBalances(src map[string]*model.DDecimal) map[string]*model.DDecimal {
+	dst := make(map[string]*model.DDecimal, len(src))
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
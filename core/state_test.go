@@ -0,0 +1,94 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func decimal(t *testing.T, s string) *model.DDecimal {
+	t.Helper()
+	d, _, err := model.NewDecimalFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+const (
+	seller = "0x00000000000000000000000000000000000000aa"
+	market = "0x00000000000000000000000000000000000000cc"
+)
+
+var buyer = common.HexToAddress("0x00000000000000000000000000000000000000AB")
+
+// settle imports a state at block 10 with a listing of 2 rose and settles it in block 11
+func settle(t *testing.T) {
+	t.Helper()
+	event := &model.RRCListedEvent{From: common.HexToAddress(seller), To: buyer, Id: [32]byte{1}}
+	ImportState(&State{
+		Version:           StateVersion,
+		LatestBlockNumber: 10,
+		Tokens:            map[string]*model.Token{"rose": {Tick: "rose", Holders: 1}},
+		TokenHolders:      map[string]map[string]*model.DDecimal{"rose": {seller: decimal(t, "5")}},
+		Balances:          map[string]map[string]*model.DDecimal{seller: {"rose": decimal(t, "5")}},
+		Lists: map[string]*model.ListedRecord{event.Hash(): {
+			Hash: event.Hash(), Tick: "rose", OriginAddr: seller, ListedTo: market, Amount: decimal(t, "2"),
+		}},
+	})
+
+	stateLock.Lock()
+	valid, err := handleRRCListEvent("0x01", common.HexToAddress(market), event, 0)
+	stateLock.Unlock()
+	if err != nil || valid != model.ValidCodeOK {
+		t.Fatalf("settlement = %d, %v", valid, err)
+	}
+}
+
+func TestSettlementBuyerKey(t *testing.T) {
+	defer func(height uint64) {
+		StrictParsingHeight = height
+		ImportState(&State{})
+	}(StrictParsingHeight)
+
+	tests := []struct {
+		name   string
+		height uint64
+		key    string
+	}{
+		{"before the strict height", 12, buyer.Hex()},
+		{"at the strict height", 11, strings.ToLower(buyer.Hex())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			StrictParsingHeight = tt.height
+			settle(t)
+
+			holders := tokenHolders["rose"]
+			if len(holders) != 2 || holders[tt.key] == nil || holders[tt.key].String() != "2" {
+				t.Fatalf("holders = %v, want 2 under %s", holders, tt.key)
+			}
+			if token, _ := QueryToken("rose"); token.Holders != 2 {
+				t.Fatalf("token holders = %d, want 2", token.Holders)
+			}
+		})
+	}
+}
+
+func TestSettlementJournalsLowercaseBuyer(t *testing.T) {
+	defer func(height uint64) {
+		StrictParsingHeight = height
+		ImportState(&State{})
+	}(StrictParsingHeight)
+	StrictParsingHeight = 0
+	settle(t)
+
+	for _, address := range []string{buyer.Hex(), strings.ToLower(buyer.Hex())} {
+		history, total, _ := QueryBalanceHistory(address, "rose", 0, -1)
+		if total != 1 || history[0].Address != strings.ToLower(buyer.Hex()) || history[0].Balance.String() != "2" {
+			t.Fatalf("history of %s = %v", address, history)
+		}
+	}
+}
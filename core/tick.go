@@ -0,0 +1,69 @@
+package core
+
+import (
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+)
+
+// normalizedTicks maps each normalized tick to the key of the first token deployed with it, guarded by stateLock
+var normalizedTicks = make(map[string]string)
+
+// tickKey returns the key an operation of block refers to tick by. Before StrictParsingHeight it is the
+// lowercase tick, the legacy rule, from then on the key of the deployed token it resolves to with
+// lookupTick, or the normalized tick a deploy would use.
+func tickKey(tick string, block uint64) string {
+	if block < StrictParsingHeight {
+		return strings.ToLower(tick)
+	}
+	if lowerTick, ok := lookupTick(tick); ok {
+		return lowerTick
+	}
+	return model.NormalizeTick(tick)
+}
+
+// lookupTick returns the key of the deployed token tick refers to. The lowercase tick comes first, it is
+// the key of every token deployed before strict mode, then the first token with the same normalized tick.
+func lookupTick(tick string) (string, bool) {
+	if lowerTick := strings.ToLower(tick); tokens[lowerTick] != nil {
+		return lowerTick, true
+	}
+	lowerTick, ok := normalizedTicks[model.NormalizeTick(tick)]
+	return lowerTick, ok
+}
+
+// indexTick adds a deployed token to the normalized and skeleton indexes,
+// it returns the key of the earlier token it renders like, "" if there is none
+func indexTick(lowerTick string) string {
+	normalized := model.NormalizeTick(lowerTick)
+	if _, ok := normalizedTicks[normalized]; !ok {
+		normalizedTicks[normalized] = lowerTick
+	}
+	skeleton := model.TickSkeleton(normalized)
+	if other, ok := tickSkeletons[skeleton]; ok {
+		return other
+	}
+	tickSkeletons[skeleton] = lowerTick
+	return ""
+}
+
+// sameTick reports whether a and b refer to the same token, or normalize alike if either is not deployed
+func sameTick(a string, b string) bool {
+	lowerA, okA := lookupTick(a)
+	lowerB, okB := lookupTick(b)
+	if okA && okB {
+		return lowerA == lowerB
+	}
+	return model.NormalizeTick(a) == model.NormalizeTick(b)
+}
+
+// TickKey returns the key of the deployed token tick refers to, its normalized form if there is none.
+// Two ticks refer to the same token when their keys are equal.
+func TickKey(tick string) string {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	if lowerTick, ok := lookupTick(tick); ok {
+		return lowerTick
+	}
+	return model.NormalizeTick(tick)
+}
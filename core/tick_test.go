@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestTickActivation(t *testing.T) {
+	defer func(height uint64) {
+		StrictParsingHeight = height
+		ImportState(&State{})
+	}(StrictParsingHeight)
+	StrictParsingHeight = 100
+	ImportState(&State{})
+
+	const (
+		deploy = `{"p":"rrc-20","op":"deploy","tick":"%s","max":"1000","lim":"10"}`
+		mint   = `{"p":"rrc-20","op":"mint","tick":"%s","amt":"1"}`
+	)
+	steps := []struct {
+		name  string
+		block uint64
+		op    string
+		tick  string
+		valid model.ValideCode
+		// key is the token the operation applies to
+		key        string
+		confusable bool
+	}{
+		{"legacy fullwidth deploy keeps its lowercase key", 10, deploy, "ＲＯＳＥ", 1, "ｒｏｓｅ", false},
+		{"legacy deploy of the folded form is another token", 11, deploy, "rose", 1, "rose", false},
+		{"legacy confusable deploy is not flagged", 12, deploy, "rоse", 1, "rоse", false},
+		{"legacy mint of the fullwidth form", 13, mint, "ＲＯＳＥ", 1, "ｒｏｓｅ", false},
+		{"legacy mint matches the lowercase only", 14, mint, "Ｒose", model.ValidCodeTokenNotExists, "", false},
+		{"strict deploy of a normalized duplicate", 100, deploy, "Ｒose", -17, "", false},
+		{"strict deploy of a legacy tick", 100, deploy, "ＲＯＳＥ", -17, "", false},
+		{"strict mint of a legacy tick keeps its token", 101, mint, "ＲＯＳＥ", 1, "ｒｏｓｅ", false},
+		{"strict mint resolves the normalized form", 101, mint, "Ｒose", 1, "ｒｏｓｅ", false},
+		{"strict deploy is keyed by the normalized tick", 102, deploy, "Straße", 1, "strasse", false},
+		{"strict mint of a case folded form", 103, mint, "STRASSE", 1, "strasse", false},
+		{"strict confusable deploy is flagged", 104, deploy, "ѕtrasse", 1, "ѕtrasse", true},
+		{"strict confusable deploy of a new tick", 104, deploy, "gеm", 1, "gеm", false},
+		{"strict deploy rendering like it is flagged", 105, deploy, "gem", 1, "gem", true},
+	}
+	for i, step := range steps {
+		before := len(rrc20Records)
+		inscription := &model.Inscription{
+			Number:  uint64(i),
+			Hash:    fmt.Sprintf("0x%064x", i),
+			From:    "0x0000000000000000000000000000000000000001",
+			To:      "0x0000000000000000000000000000000000000001",
+			Block:   step.block,
+			Content: fmt.Sprintf(step.op, step.tick),
+		}
+		if _, err := handleProtocols(inscription); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if len(rrc20Records) != before+1 {
+			t.Fatalf("%s: no record", step.name)
+		}
+		if valid := rrc20Records[before].Valid; valid != step.valid {
+			t.Fatalf("%s: valid = %d, want %d", step.name, valid, step.valid)
+		}
+		if step.key == "" {
+			continue
+		}
+		token, ok := tokens[step.key]
+		if !ok {
+			t.Fatalf("%s: no token %q", step.name, step.key)
+		}
+		if token.Confusable != step.confusable {
+			t.Fatalf("%s: confusable = %v", step.name, token.Confusable)
+		}
+	}
+
+	if minted := tokens["ｒｏｓｅ"].Minted.String(); minted != "3" {
+		t.Fatalf("ｒｏｓｅ minted %s, want 3", minted)
+	}
+	if minted := tokens["rose"].Minted.String(); minted != "0" {
+		t.Fatalf("rose minted %s, want 0", minted)
+	}
+	if _, ok := QueryToken("ＳＴＲＡＳＳＥ"); !ok {
+		t.Fatal("query by a normalized form")
+	}
+}
+
+func TestNormalizeTick(t *testing.T) {
+	tests := []struct {
+		tick, normalized, skeleton string
+	}{
+		{"rose", "rose", "rose"},
+		{"ROSE", "rose", "rose"},
+		{"ＲＯＳＥ", "rose", "rose"},
+		{"Straße", "strasse", "strasse"},
+		{"rоse", "rоse", "rose"},
+		{"ɡem", "ɡem", "gem"},
+		{"ﬁre", "fire", "fire"},
+	}
+	for _, tt := range tests {
+		normalized := model.NormalizeTick(tt.tick)
+		if normalized != tt.normalized {
+			t.Errorf("NormalizeTick(%q) = %q, want %q", tt.tick, normalized, tt.normalized)
+		}
+		if skeleton := model.TickSkeleton(normalized); skeleton != tt.skeleton {
+			t.Errorf("TickSkeleton(%q) = %q, want %q", normalized, skeleton, tt.skeleton)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package diverge finds the first block two indexer states disagree on and what they disagree about
+package diverge
+
+import (
+	"fmt"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strconv"
+)
+
+// Divergence is the first block the state roots of two sources differ at
+type Divergence struct {
+	Block uint64
+	// AtStart is set when the roots already differ at the first compared block,
+	// the sources may have diverged before it
+	AtStart bool
+	A       *model.BlockRoot
+	B       *model.BlockRoot
+}
+
+// Range returns the blocks both sources have state roots for, from is used when their first one is unknown
+func Range(a Source, b Source, from uint64) (uint64, uint64, error) {
+	firstA, lastA, err := a.Heights()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", a, err)
+	}
+	firstB, lastB, err := b.Heights()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", b, err)
+	}
+	lo, hi := from, lastA
+	if firstA > lo {
+		lo = firstA
+	}
+	if firstB > lo {
+		lo = firstB
+	}
+	if lastB < hi {
+		hi = lastB
+	}
+	if lo > hi {
+		return 0, 0, fmt.Errorf("no common blocks, %s has %d to %d and %s %d to %d", a, firstA, lastA, b, firstB, lastB)
+	}
+	return lo, hi, nil
+}
+
+// Find binary searches [lo, hi] for the first block whose state roots differ, nil if they agree at hi.
+// Once the states differ they are assumed to keep differing.
+func Find(a Source, b Source, lo uint64, hi uint64) (*Divergence, error) {
+	differs := func(block uint64) (*Divergence, error) {
+		rootA, err := a.Root(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s at %d: %w", a, block, err)
+		}
+		rootB, err := b.Root(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s at %d: %w", b, block, err)
+		}
+		if rootA.StateRoot == rootB.StateRoot {
+			return nil, nil
+		}
+		return &Divergence{Block: block, A: rootA, B: rootB}, nil
+	}
+
+	last, err := differs(hi)
+	if err != nil || last == nil {
+		return nil, err
+	}
+	first, err := differs(lo)
+	if err != nil {
+		return nil, err
+	}
+	if first != nil {
+		first.AtStart = true
+		return first, nil
+	}
+	// the roots agree at lo and differ at hi
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		div, err := differs(mid)
+		if err != nil {
+			return nil, err
+		}
+		if div != nil {
+			hi, last = mid, div
+		} else {
+			lo = mid
+		}
+	}
+	return last, nil
+}
+
+// BalanceDiff is a balance the sources disagree on, empty when a source has none
+type BalanceDiff struct {
+	Tick    string
+	Address string
+	A       string
+	B       string
+}
+
+func DiffBalances(a Balances, b Balances) []*BalanceDiff {
+	var res []*BalanceDiff
+	for lowerTick, holders := range a {
+		for address, balance := range holders {
+			if other := b[lowerTick][address]; other != balance {
+				res = append(res, &BalanceDiff{Tick: lowerTick, Address: address, A: balance, B: other})
+			}
+		}
+	}
+	for lowerTick, holders := range b {
+		for address, balance := range holders {
+			if _, ok := a[lowerTick][address]; !ok {
+				res = append(res, &BalanceDiff{Tick: lowerTick, Address: address, B: balance})
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Tick != res[j].Tick {
+			return res[i].Tick < res[j].Tick
+		}
+		return res[i].Address < res[j].Address
+	})
+	return res
+}
+
+// FieldDiff is a token or listing field the sources disagree on, Key is the tick or the listing hash.
+// Field is empty when only one source has the entry.
+type FieldDiff struct {
+	Key   string
+	Field string
+	A     string
+	B     string
+}
+
+// tokenFields are the token fields in the state root
+func tokenFields(token *model.Token) [][2]string {
+	return [][2]string{
+		{"Tick", token.Tick},
+		{"Number", strconv.FormatUint(token.Number, 10)},
+		{"Precision", strconv.Itoa(token.Precision)},
+		{"Max", token.Max.String()},
+		{"Limit", token.Limit.String()},
+		{"Minted", token.Minted.String()},
+		{"Holders", strconv.FormatInt(int64(token.Holders), 10)},
+		{"Trxs", strconv.FormatInt(int64(token.Trxs), 10)},
+		{"CreatedAt", strconv.FormatUint(token.CreatedAt, 10)},
+		{"DeployAddress", token.DeployAddress},
+		{"DeployHash", token.DeployHash},
+	}
+}
+
+// listFields are the listing fields in the state root
+func listFields(listRec *model.ListedRecord) [][2]string {
+	return [][2]string{
+		{"Tick", listRec.Tick},
+		{"OriginAddr", listRec.OriginAddr},
+		{"ListedTo", listRec.ListedTo},
+		{"Amount", listRec.Amount.String()},
+		{"ListedTs", strconv.FormatUint(listRec.ListedTs, 10)},
+	}
+}
+
+func DiffTokens(a map[string]*model.Token, b map[string]*model.Token) []*FieldDiff {
+	return diffFields(a, b, tokenFields)
+}
+
+func DiffLists(a map[string]*model.ListedRecord, b map[string]*model.ListedRecord) []*FieldDiff {
+	return diffFields(a, b, listFields)
+}
+
+func diffFields[T any](a map[string]T, b map[string]T, fields func(T) [][2]string) []*FieldDiff {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var res []*FieldDiff
+	for _, key := range sorted {
+		valueA, okA := a[key]
+		valueB, okB := b[key]
+		switch {
+		case !okA:
+			res = append(res, &FieldDiff{Key: key, B: "present"})
+		case !okB:
+			res = append(res, &FieldDiff{Key: key, A: "present"})
+		default:
+			fieldsA, fieldsB := fields(valueA), fields(valueB)
+			for i := range fieldsA {
+				if fieldsA[i][1] != fieldsB[i][1] {
+					res = append(res, &FieldDiff{Key: key, Field: fieldsA[i][0], A: fieldsA[i][1], B: fieldsB[i][1]})
+				}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,130 @@
+package diverge
+
+import (
+	"errors"
+	"fmt"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/merkle"
+	"testing"
+)
+
+// fakeSource has roots for first to last that change from the diverged block on, 0 never
+type fakeSource struct {
+	name        string
+	first, last uint64
+	diverged    uint64
+}
+
+func (s *fakeSource) String() string { return s.name }
+
+func (s *fakeSource) Heights() (uint64, uint64, error) { return s.first, s.last, nil }
+
+func (s *fakeSource) Root(block uint64) (*model.BlockRoot, error) {
+	if block < s.first || block > s.last {
+		return nil, ErrNoRoot
+	}
+	root := &model.BlockRoot{Number: block, StateRoot: merkle.Hash{1}}
+	if s.diverged != 0 && block >= s.diverged {
+		root.StateRoot = merkle.Hash{2}
+	}
+	return root, nil
+}
+
+func (s *fakeSource) Balances(block uint64) (Balances, error) { return nil, nil }
+
+func (s *fakeSource) Latest() (*Latest, error) { return nil, nil }
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name     string
+		lo, hi   uint64
+		diverged uint64
+		block    uint64
+		atStart  bool
+	}{
+		{"agree", 10, 20, 0, 0, false},
+		{"agree at hi", 10, 20, 21, 0, false},
+		{"differ at hi only", 10, 20, 20, 20, false},
+		{"differ in the middle", 10, 20, 13, 13, false},
+		{"differ after lo", 10, 20, 11, 11, false},
+		{"differ at lo", 10, 20, 10, 10, true},
+		{"differ before lo", 10, 20, 5, 10, true},
+		{"single block agree", 10, 10, 11, 0, false},
+		{"single block differ", 10, 10, 10, 10, true},
+		{"two blocks", 10, 11, 11, 11, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeSource{name: "a", first: 1, last: 100}
+			b := &fakeSource{name: "b", first: 1, last: 100, diverged: tt.diverged}
+			div, err := Find(a, b, tt.lo, tt.hi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.block == 0 {
+				if div != nil {
+					t.Fatalf("Find = block %d, want none", div.Block)
+				}
+				return
+			}
+			if div == nil {
+				t.Fatalf("Find = none, want block %d", tt.block)
+			}
+			if div.Block != tt.block || div.AtStart != tt.atStart || div.A.Number != tt.block || div.B.Number != tt.block {
+				t.Fatalf("Find = block %d at start %v, want %d at start %v", div.Block, div.AtStart, tt.block, tt.atStart)
+			}
+		})
+	}
+}
+
+func TestFindMissingRoot(t *testing.T) {
+	a := &fakeSource{name: "a", first: 1, last: 100}
+	b := &fakeSource{name: "b", first: 1, last: 15, diverged: 12}
+	if _, err := Find(a, b, 10, 20); !errors.Is(err, ErrNoRoot) {
+		t.Fatalf("Find err = %v, want ErrNoRoot", err)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		a, b   *fakeSource
+		from   uint64
+		lo, hi uint64
+		err    bool
+	}{
+		{&fakeSource{first: 1, last: 100}, &fakeSource{first: 1, last: 100}, 0, 1, 100, false},
+		{&fakeSource{first: 10, last: 100}, &fakeSource{first: 20, last: 50}, 0, 20, 50, false},
+		{&fakeSource{first: 0, last: 100}, &fakeSource{first: 0, last: 80}, 30, 30, 80, false},
+		{&fakeSource{first: 1, last: 10}, &fakeSource{first: 20, last: 30}, 0, 0, 0, true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			lo, hi, err := Range(tt.a, tt.b, tt.from)
+			if (err != nil) != tt.err {
+				t.Fatalf("Range err = %v", err)
+			}
+			if lo != tt.lo || hi != tt.hi {
+				t.Fatalf("Range = %d, %d, want %d, %d", lo, hi, tt.lo, tt.hi)
+			}
+		})
+	}
+}
+
+func TestDiffBalances(t *testing.T) {
+	a := Balances{"rose": {"0xaa": "1", "0xbb": "2"}}
+	b := Balances{"rose": {"0xaa": "1", "0xbb": "3", "0xcc": "4"}, "gem": {"0xaa": "5"}}
+	diffs := DiffBalances(a, b)
+	want := []BalanceDiff{
+		{Tick: "gem", Address: "0xaa", B: "5"},
+		{Tick: "rose", Address: "0xbb", A: "2", B: "3"},
+		{Tick: "rose", Address: "0xcc", B: "4"},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("DiffBalances = %d diffs, want %d", len(diffs), len(want))
+	}
+	for i, diff := range diffs {
+		if *diff != want[i] {
+			t.Errorf("diff %d = %+v, want %+v", i, *diff, want[i])
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package diverge
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	pageLimit      = 100
+	requestTimeout = 30 * time.Second
+)
+
+var ErrNoRoot = errors.New("no state root")
+
+// Balances are the non-zero balances by lowercased tick and address
+type Balances map[string]map[string]string
+
+// Latest is the tokens and listings of a source at its last indexed block,
+// they are only kept for that block
+type Latest struct {
+	Block  uint64
+	Tokens map[string]*model.Token
+	Lists  map[string]*model.ListedRecord
+}
+
+// Source is an indexer state to compare
+type Source interface {
+	String() string
+	// Heights returns the first and last block with a state root, first is 0 if unknown
+	Heights() (uint64, uint64, error)
+	// Root returns the state root of block, ErrNoRoot if there is none
+	Root(block uint64) (*model.BlockRoot, error)
+	Balances(block uint64) (Balances, error)
+	Latest() (*Latest, error)
+}
+
+// StateSource reads a saved state, a checkpoint or a json dump of one
+type StateSource struct {
+	name  string
+	state *core.State
+}
+
+func NewStateSource(name string, state *core.State) *StateSource {
+	return &StateSource{name: name, state: state}
+}
+
+// LoadStateFile reads a state dump, gzip'd if the name ends with .gz
+func LoadStateFile(path string) (*StateSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	var state core.State
+	if err := json.NewDecoder(reader).Decode(&state); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return NewStateSource(path, &state), nil
+}
+
+func (s *StateSource) String() string {
+	return s.name
+}
+
+func (s *StateSource) Heights() (uint64, uint64, error) {
+	roots := s.state.BlockRoots
+	if len(roots) == 0 {
+		return 0, 0, ErrNoRoot
+	}
+	return roots[0].Number, roots[len(roots)-1].Number, nil
+}
+
+func (s *StateSource) Root(block uint64) (*model.BlockRoot, error) {
+	roots := s.state.BlockRoots
+	i := sort.Search(len(roots), func(i int) bool {
+		return roots[i].Number >= block
+	})
+	if i == len(roots) || roots[i].Number != block {
+		return nil, ErrNoRoot
+	}
+	return roots[i], nil
+}
+
+// Balances replays the balance journal up to block
+func (s *StateSource) Balances(block uint64) (Balances, error) {
+	if block > s.state.LatestBlockNumber {
+		return nil, core.ErrorBlockNotIndexed
+	}
+	res := make(Balances)
+	for _, change := range s.state.BalanceChanges {
+		if change.Block > block {
+			continue
+		}
+		lowerTick := tickID(change.Tick)
+		if res[lowerTick] == nil {
+			res[lowerTick] = make(map[string]string)
+		}
+		res[lowerTick][change.Address] = change.Balance.String()
+	}
+	for _, holders := range res {
+		for address, balance := range holders {
+			if balance == "0" {
+				delete(holders, address)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *StateSource) Latest() (*Latest, error) {
+	latest := &Latest{
+		Block:  s.state.LatestBlockNumber,
+		Tokens: make(map[string]*model.Token, len(s.state.Tokens)),
+		Lists:  s.state.Lists,
+	}
+	for _, token := range s.state.Tokens {
+		latest.Tokens[tickID(token.Tick)] = token
+	}
+	return latest, nil
+}
+
+// tickID identifies a token across sources. The deployed tick lowercased is unique per token,
+// whichever key the indexer keeps it under.
+func tickID(tick string) string {
+	return strings.ToLower(tick)
+}
+
+// HTTPSource reads the REST API of another instance
+type HTTPSource struct {
+	base   string
+	client *http.Client
+}
+
+func NewHTTPSource(base string) *HTTPSource {
+	return &HTTPSource{
+		base:   strings.TrimSuffix(base, "/"),
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (s *HTTPSource) String() string {
+	return s.base
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type pageResponse struct {
+	Total int             `json:"total"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// get decodes the data of the response, ok is false on 404
+func (s *HTTPSource) get(path string, query url.Values, data interface{}) (bool, error) {
+	u := s.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	resp, err := s.client.Get(u)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		var res errorResponse
+		json.NewDecoder(resp.Body).Decode(&res)
+		return false, fmt.Errorf("GET %s: %s %s", u, resp.Status, res.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return false, fmt.Errorf("GET %s: %w", u, err)
+	}
+	return true, nil
+}
+
+// getAll collects the pages of a list endpoint, add decodes the data of each page and returns its length
+func (s *HTTPSource) getAll(path string, query url.Values, add func(data json.RawMessage) (int, error)) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("limit", fmt.Sprint(pageLimit))
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprint(page))
+		var res pageResponse
+		ok, err := s.get(path, query, &res)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("GET %s: not found", s.base+path)
+		}
+		n, err := add(res.Data)
+		if err != nil {
+			return err
+		}
+		if n == 0 || page*pageLimit >= res.Total {
+			return nil
+		}
+	}
+}
+
+func (s *HTTPSource) root(block string) (*model.BlockRoot, error) {
+	var res struct {
+		Data *model.BlockRoot `json:"data"`
+	}
+	ok, err := s.get("/api/v1/blocks/"+block+"/root", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoRoot
+	}
+	return res.Data, nil
+}
+
+// Heights only knows the last block, the api doesn't tell since when roots are kept
+func (s *HTTPSource) Heights() (uint64, uint64, error) {
+	root, err := s.root("latest")
+	if err != nil {
+		return 0, 0, err
+	}
+	return 0, root.Number, nil
+}
+
+func (s *HTTPSource) Root(block uint64) (*model.BlockRoot, error) {
+	return s.root(fmt.Sprint(block))
+}
+
+func (s *HTTPSource) tokens() ([]*model.Token, error) {
+	var tokens []*model.Token
+	err := s.getAll("/api/v1/tokens", nil, func(data json.RawMessage) (int, error) {
+		var page []*model.Token
+		if err := json.Unmarshal(data, &page); err != nil {
+			return 0, err
+		}
+		tokens = append(tokens, page...)
+		return len(page), nil
+	})
+	return tokens, err
+}
+
+func (s *HTTPSource) Balances(block uint64) (Balances, error) {
+	tokens, err := s.tokens()
+	if err != nil {
+		return nil, err
+	}
+	res := make(Balances)
+	for _, token := range tokens {
+		lowerTick := tickID(token.Tick)
+		holders := make(map[string]string)
+		query := url.Values{"block": {fmt.Sprint(block)}}
+		err := s.getAll("/api/v1/tokens/"+url.PathEscape(token.Tick)+"/holders", query, func(data json.RawMessage) (int, error) {
+			var page []*core.Holder
+			if err := json.Unmarshal(data, &page); err != nil {
+				return 0, err
+			}
+			for _, holder := range page {
+				holders[holder.Address] = holder.Balance.String()
+			}
+			return len(page), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(holders) > 0 {
+			res[lowerTick] = holders
+		}
+	}
+	return res, nil
+}
+
+func (s *HTTPSource) Latest() (*Latest, error) {
+	root, err := s.root("latest")
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokens()
+	if err != nil {
+		return nil, err
+	}
+	latest := &Latest{
+		Block:  root.Number,
+		Tokens: make(map[string]*model.Token, len(tokens)),
+		Lists:  make(map[string]*model.ListedRecord),
+	}
+	for _, token := range tokens {
+		latest.Tokens[tickID(token.Tick)] = token
+	}
+	err = s.getAll("/api/v1/listings", nil, func(data json.RawMessage) (int, error) {
+		var page []*model.ListedRecord
+		if err := json.Unmarshal(data, &page); err != nil {
+			return 0, err
+		}
+		for _, listRec := range page {
+			latest.Lists[listRec.Hash] = listRec
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
@@ -0,0 +1,158 @@
+package events
+
+import (
+	"fmt"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"strconv"
+	"strings"
+)
+
+type EventType string
+
+const (
+	EventInscription EventType = "inscription"
+	// EventOperation is a deploy, mint or transfer, valid or not
+	EventOperation  EventType = "operation"
+	EventListing    EventType = "listing"
+	EventSettlement EventType = "settlement"
+)
+
+// Cursor is the position of an event, the index counts the events of its block
+type Cursor struct {
+	Block uint64
+	Index int
+}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d:%d", c.Block, c.Index)
+}
+
+// After reports whether c comes after other
+func (c Cursor) After(other Cursor) bool {
+	return c.Block > other.Block || (c.Block == other.Block && c.Index > other.Index)
+}
+
+// endOfBlock is the cursor after every event of block
+func endOfBlock(block uint64) Cursor {
+	return Cursor{Block: block, Index: int(^uint(0) >> 1)}
+}
+
+// ParseCursor parses "block:index", a bare block number resumes after the whole block
+func ParseCursor(s string) (Cursor, error) {
+	blockStr, indexStr, hasIndex := strings.Cut(s, ":")
+	block, err := strconv.ParseUint(blockStr, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	if !hasIndex {
+		return endOfBlock(block), nil
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 {
+		return Cursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	return Cursor{Block: block, Index: index}, nil
+}
+
+type Event struct {
+	Cursor      string
+	Type        EventType
+	Block       uint64
+	Timestamp   uint64
+	TxHash      string
+	Tick        string               `json:",omitempty"`
+	From        string               `json:",omitempty"`
+	To          string               `json:",omitempty"`
+	Operation   model.RRC20Operation `json:",omitempty"`
+	Valid       model.ValideCode     `json:",omitempty"`
+	Inscription *model.Inscription   `json:",omitempty"`
+	Record      *model.RRC20         `json:",omitempty"`
+
+	cursor Cursor
+}
+
+// buildEvents turns a committed block into events in processing order:
+// each inscription followed by its rrc-20 operation, then the settlements from receipts.
+func buildEvents(result *core.BlockResult) []*Event {
+	var res []*Event
+	add := func(event *Event) {
+		event.cursor = Cursor{Block: result.Number, Index: len(res)}
+		event.Cursor = event.cursor.String()
+		event.Block = result.Number
+		event.Timestamp = result.Timestamp
+		res = append(res, event)
+	}
+
+	records := result.Records
+	for _, inscription := range result.Inscriptions {
+		add(&Event{
+			Type:        EventInscription,
+			TxHash:      inscription.Hash,
+			From:        strings.ToLower(inscription.From),
+			To:          strings.ToLower(inscription.To),
+			Inscription: inscription,
+		})
+		for len(records) > 0 && records[0].Operation != model.RRC20OperationExchange && records[0].Hash == inscription.Hash {
+			add(recordEvent(records[0]))
+			records = records[1:]
+		}
+	}
+	for _, rrc20 := range records {
+		add(recordEvent(rrc20))
+	}
+	return res
+}
+
+func recordEvent(rrc20 *model.RRC20) *Event {
+	eventType := EventOperation
+	switch rrc20.Operation {
+	case model.RRC20OperationList:
+		eventType = EventListing
+	case model.RRC20OperationExchange:
+		eventType = EventSettlement
+	}
+	return &Event{
+		Type:      eventType,
+		TxHash:    rrc20.Hash,
+		Tick:      rrc20.Tick,
+		From:      rrc20.From,
+		To:        rrc20.To,
+		Operation: rrc20.Operation,
+		Valid:     rrc20.Valid,
+		Record:    rrc20,
+	}
+}
+
+// Filter selects events, empty fields match everything
+type Filter struct {
+	Tick       string
+	Address    string
+	Types      []EventType
+	Operations []model.RRC20Operation
+}
+
+func (f *Filter) Match(event *Event) bool {
+	if f.Tick != "" && core.TickKey(f.Tick) != core.TickKey(event.Tick) {
+		return false
+	}
+	if f.Address != "" && !strings.EqualFold(f.Address, event.From) && !strings.EqualFold(f.Address, event.To) {
+		return false
+	}
+	if len(f.Types) > 0 && !contains(f.Types, event.Type) {
+		return false
+	}
+	if len(f.Operations) > 0 && !contains(f.Operations, event.Operation) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		s      string
+		cursor Cursor
+		err    bool
+	}{
+		{"10:3", Cursor{Block: 10, Index: 3}, false},
+		{"10:0", Cursor{Block: 10}, false},
+		{"10", endOfBlock(10), false},
+		{"10:-1", Cursor{}, true},
+		{"10:x", Cursor{}, true},
+		{"x:1", Cursor{}, true},
+		{"", Cursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			cursor, err := ParseCursor(tt.s)
+			if (err != nil) != tt.err || cursor != tt.cursor {
+				t.Fatalf("ParseCursor = %v, %v, want %v", cursor, err, tt.cursor)
+			}
+			if err == nil && tt.s != "10" && cursor.String() != tt.s {
+				t.Fatalf("String = %s, want %s", cursor, tt.s)
+			}
+		})
+	}
+	if !endOfBlock(10).After(Cursor{Block: 10, Index: 100}) || endOfBlock(10).After(Cursor{Block: 11}) {
+		t.Fatal("end of block is not after every event of the block")
+	}
+}
+
+func testBlock(number uint64) *core.BlockResult {
+	return &core.BlockResult{
+		Number:    number,
+		Timestamp: 1000 + number,
+		Inscriptions: []*model.Inscription{
+			{Hash: "0x01", From: "0xAA", To: "0xBB"},
+			{Hash: "0x02", From: "0xCC", To: "0xDD"},
+		},
+		Records: []*model.RRC20{
+			{Hash: "0x01", Tick: "ROSE", From: "0xaa", To: "0xbb", Operation: model.RRC20OperationTransfer, Valid: 1},
+			{Hash: "0x02", Tick: "rose", From: "0xcc", To: "0xdd", Operation: model.RRC20OperationList, Valid: 1},
+			{Hash: "0x03", Tick: "gem", From: "0xdd", To: "0xee", Operation: model.RRC20OperationExchange, Valid: 1},
+		},
+	}
+}
+
+func TestBuildEvents(t *testing.T) {
+	events := buildEvents(testBlock(7))
+	want := []struct {
+		eventType EventType
+		txHash    string
+	}{
+		{EventInscription, "0x01"},
+		{EventOperation, "0x01"},
+		{EventInscription, "0x02"},
+		{EventListing, "0x02"},
+		{EventSettlement, "0x03"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i].eventType || event.TxHash != want[i].txHash {
+			t.Errorf("event %d = %s %s, want %s %s", i, event.Type, event.TxHash, want[i].eventType, want[i].txHash)
+		}
+		if event.cursor != (Cursor{Block: 7, Index: i}) || event.Cursor != event.cursor.String() || event.Block != 7 || event.Timestamp != 1007 {
+			t.Errorf("event %d at %s, block %d, timestamp %d", i, event.Cursor, event.Block, event.Timestamp)
+		}
+	}
+	if events[0].From != "0xaa" || events[0].To != "0xbb" {
+		t.Errorf("inscription addresses %s %s not lowercased", events[0].From, events[0].To)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	event := &Event{Type: EventOperation, Tick: "ROSE", From: "0xaa", To: "0xbb", Operation: model.RRC20OperationTransfer}
+	tests := []struct {
+		name   string
+		filter Filter
+		match  bool
+	}{
+		{"empty", Filter{}, true},
+		{"tick in another case", Filter{Tick: "Rose"}, true},
+		{"other tick", Filter{Tick: "gem"}, false},
+		{"sender", Filter{Address: "0xAA"}, true},
+		{"receiver", Filter{Address: "0xbb"}, true},
+		{"other address", Filter{Address: "0xcc"}, false},
+		{"type", Filter{Types: []EventType{EventListing, EventOperation}}, true},
+		{"other type", Filter{Types: []EventType{EventListing}}, false},
+		{"operation", Filter{Operations: []model.RRC20Operation{model.RRC20OperationTransfer}}, true},
+		{"other operation", Filter{Operations: []model.RRC20Operation{model.RRC20OperationMint}}, false},
+		{"every field", Filter{Tick: "rose", Address: "0xbb", Types: []EventType{EventOperation}, Operations: []model.RRC20Operation{model.RRC20OperationTransfer}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(event); got != tt.match {
+				t.Fatalf("Match = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	// the stream is public read-only data
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// parseRequest reads the filter from the tick, address, type and op query parameters,
+// type and op take comma separated lists. The resume cursor is the cursor parameter
+// or the Last-Event-ID header sent by reconnecting EventSource clients.
+func parseRequest(r *http.Request) (Filter, *Cursor, error) {
+	query := r.URL.Query()
+	filter := Filter{
+		Tick:    query.Get("tick"),
+		Address: query.Get("address"),
+	}
+	for _, value := range splitList(query.Get("type")) {
+		filter.Types = append(filter.Types, EventType(value))
+	}
+	for _, value := range splitList(query.Get("op")) {
+		filter.Operations = append(filter.Operations, model.RRC20Operation(value))
+	}
+
+	cursorStr := query.Get("cursor")
+	if cursorStr == "" {
+		cursorStr = r.Header.Get("Last-Event-ID")
+	}
+	if cursorStr == "" {
+		return filter, nil, nil
+	}
+	cursor, err := ParseCursor(cursorStr)
+	if err != nil {
+		return filter, nil, err
+	}
+	return filter, &cursor, nil
+}
+
+func splitList(value string) []string {
+	var res []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func (h *Hub) subscribeRequest(w http.ResponseWriter, r *http.Request) (*Subscription, []*Event, bool) {
+	filter, cursor, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	sub, backlog, err := h.Subscribe(filter, cursor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return nil, nil, false
+	}
+	return sub, backlog, true
+}
+
+// ServeSSE streams events as Server-Sent Events, the event id is the cursor
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub, backlog, ok := h.subscribeRequest(w, r)
+	if !ok {
+		return
+	}
+	defer h.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event *Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Cursor, event.Type, data)
+		return err
+	}
+
+	for _, event := range backlog {
+		if err := write(event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := write(event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// ServeWebSocket streams events as json text messages
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub, backlog, ok := h.subscribeRequest(w, r)
+	if !ok {
+		return
+	}
+	defer h.Unsubscribe(sub)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logrus.Warnf("websocket upgrade err: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// the client sends nothing, reading only detects the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(event *Event) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(event)
+	}
+
+	for _, event := range backlog {
+		if err := write(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber too slow"), time.Now().Add(writeTimeout))
+				return
+			}
+			if err := write(event); err != nil {
+				return
+			}
+		}
+	}
+}
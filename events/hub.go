@@ -0,0 +1,127 @@
+package events
+
+import (
+	"errors"
+	"rose-scriptions-open-indexer/core"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultHistorySize = 10000
+
+	// subscriberBuffer is the number of events a client may lag behind before it is dropped
+	subscriberBuffer = 1024
+)
+
+var (
+	ErrCursorExpired = errors.New("cursor is older than the event history")
+)
+
+// Hub keeps the most recent events so clients can resume from a cursor, and fans new ones out
+type Hub struct {
+	lock        sync.Mutex
+	history     []*Event
+	historySize int
+	// first is the newest cursor that can't be replayed: the end of the block the hub started after,
+	// then the last event dropped from history. It is nil until the first block is published.
+	first       *Cursor
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	filter Filter
+	// since skips the events a resuming client already has when blocks are indexed again after a restart
+	since *Cursor
+	// Events delivers matching events in order, it is closed when the subscriber falls behind
+	Events chan *Event
+}
+
+func NewHub(historySize int) *Hub {
+	h := &Hub{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+	core.OnBlockCommitted(h.publish)
+	return h
+}
+
+// Subscribe returns the matching events after since, nil for only new events,
+// followed by everything published from now on.
+func (h *Hub) Subscribe(filter Filter, since *Cursor) (*Subscription, []*Event, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var backlog []*Event
+	if since != nil {
+		first := h.first
+		if first == nil {
+			// nothing published since the start, the events up to the indexed block are gone
+			started := endOfBlock(core.QueryLatestBlockNumber())
+			first = &started
+		}
+		if first.After(*since) {
+			return nil, nil, ErrCursorExpired
+		}
+		for _, event := range h.history {
+			if event.cursor.After(*since) && filter.Match(event) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		filter: filter,
+		since:  since,
+		Events: make(chan *Event, subscriberBuffer),
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, backlog, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+func (h *Hub) publish(result *core.BlockResult) {
+	events := buildEvents(result)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.first == nil {
+		started := endOfBlock(result.Number - 1)
+		h.first = &started
+	}
+	h.history = append(h.history, events...)
+	if over := len(h.history) - h.historySize; over > 0 {
+		first := h.history[over-1].cursor
+		h.first = &first
+		h.history = append([]*Event(nil), h.history[over:]...)
+	}
+
+	for sub := range h.subscribers {
+		for _, event := range events {
+			if !sub.filter.Match(event) || (sub.since != nil && !event.cursor.After(*sub.since)) {
+				continue
+			}
+			select {
+			case sub.Events <- event:
+			default:
+				logrus.Warnf("drop event subscriber at %s", event.Cursor)
+				delete(h.subscribers, sub)
+				close(sub.Events)
+			}
+			if _, ok := h.subscribers[sub]; !ok {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(8)
+	h.publish(testBlock(200))
+	h.publish(testBlock(201))
+
+	tests := []struct {
+		name    string
+		since   Cursor
+		backlog []string
+		err     error
+	}{
+		{"before the first block", endOfBlock(198), nil, ErrCursorExpired},
+		{"dropped from history", endOfBlock(199), nil, ErrCursorExpired},
+		{"last dropped event", Cursor{Block: 200, Index: 1}, []string{"200:2", "200:3", "200:4", "201:0", "201:1", "201:2", "201:3", "201:4"}, nil},
+		{"inside a block", Cursor{Block: 201, Index: 2}, []string{"201:3", "201:4"}, nil},
+		{"end of the last block", endOfBlock(201), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since := tt.since
+			sub, backlog, err := h.Subscribe(Filter{}, &since)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Subscribe err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			defer h.Unsubscribe(sub)
+			if len(backlog) != len(tt.backlog) {
+				t.Fatalf("backlog of %d events, want %d", len(backlog), len(tt.backlog))
+			}
+			for i, event := range backlog {
+				if event.Cursor != tt.backlog[i] {
+					t.Fatalf("backlog %d = %s, want %s", i, event.Cursor, tt.backlog[i])
+				}
+			}
+		})
+	}
+
+	since := Cursor{Block: 201, Index: 4}
+	sub, _, err := h.Subscribe(Filter{Types: []EventType{EventSettlement}}, &since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Unsubscribe(sub)
+	h.publish(testBlock(202))
+	if event := <-sub.Events; event.Cursor != "202:4" {
+		t.Fatalf("received %s, want 202:4", event.Cursor)
+	}
+}
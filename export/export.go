@@ -0,0 +1,157 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Row is the holding of one address for one tick. Escrow is the amount locked in its active listings,
+// Total is Balance plus Escrow.
+type Row struct {
+	Tick    string
+	Address string
+	Balance *model.DDecimal
+	Escrow  *model.DDecimal
+	Total   *model.DDecimal
+}
+
+// Snapshot is the json export
+type Snapshot struct {
+	Block uint64
+	Rows  []*Row
+}
+
+// Collect returns the holders of ticks, every token if empty, from the current state.
+// Addresses are lowercased and merged, rows are sorted by tick then total descending.
+func Collect(ticks []string) ([]*Row, error) {
+	if len(ticks) == 0 {
+		tokens, _ := core.QueryTokens(0, -1)
+		for _, token := range tokens {
+			ticks = append(ticks, token.Tick)
+		}
+	}
+
+	var res []*Row
+	for _, tick := range ticks {
+		token, ok := core.QueryToken(tick)
+		if !ok {
+			return nil, fmt.Errorf("token %s not found", tick)
+		}
+
+		rows := make(map[string]*Row)
+		row := func(address string) *Row {
+			address = strings.ToLower(address)
+			r, ok := rows[address]
+			if !ok {
+				r = &Row{
+					Tick:    token.Tick,
+					Address: address,
+					Balance: model.NewDecimal(),
+					Escrow:  model.NewDecimal(),
+				}
+				rows[address] = r
+			}
+			return r
+		}
+
+		holders, _, _ := core.QueryHolders(token.Tick, 0, -1)
+		for _, holder := range holders {
+			r := row(holder.Address)
+			r.Balance = r.Balance.Add(holder.Balance)
+		}
+		listings, _ := core.QueryListings(core.ListingFilter{Tick: token.Tick}, 0, -1)
+		for _, listRec := range listings {
+			r := row(listRec.OriginAddr)
+			r.Escrow = r.Escrow.Add(listRec.Amount)
+		}
+
+		tickRows := make([]*Row, 0, len(rows))
+		for _, r := range rows {
+			r.Total = r.Balance.Add(r.Escrow)
+			tickRows = append(tickRows, r)
+		}
+		sort.Slice(tickRows, func(i, j int) bool {
+			if cmp := tickRows[i].Total.Cmp(tickRows[j].Total); cmp != 0 {
+				return cmp > 0
+			}
+			return tickRows[i].Address < tickRows[j].Address
+		})
+		res = append(res, tickRows...)
+	}
+	return res, nil
+}
+
+func WriteCSV(w io.Writer, rows []*Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"tick", "address", "balance", "escrow", "total"}); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := cw.Write([]string{r.Tick, r.Address, r.Balance.String(), r.Escrow.String(), r.Total.String()}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteJSON(w io.Writer, block uint64, rows []*Row) error {
+	if rows == nil {
+		rows = make([]*Row, 0)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&Snapshot{Block: block, Rows: rows})
+}
+
+// WriteFile writes the snapshot to path and its sha256 to path.sha256 in the sha256sum format,
+// so it can be verified with `sha256sum -c`. It returns the hex checksum.
+func WriteFile(path string, format string, block uint64, rows []*Row) (string, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	w := io.MultiWriter(f, hash)
+	switch format {
+	case FormatCSV:
+		err = WriteCSV(w, rows)
+	case FormatJSON:
+		err = WriteJSON(w, block, rows)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	line := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(line), 0644); err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func decimal(t *testing.T, s string) *model.DDecimal {
+	t.Helper()
+	d, _, err := model.NewDecimalFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteFile(t *testing.T) {
+	rows := []*Row{
+		{Tick: "rose", Address: "0xaa", Balance: decimal(t, "1.5"), Escrow: decimal(t, "2"), Total: decimal(t, "3.5")},
+		{Tick: "rose", Address: "0xbb", Balance: decimal(t, "1"), Escrow: model.NewDecimal(), Total: decimal(t, "1")},
+	}
+	tests := []struct {
+		format string
+		rows   []*Row
+		want   string
+		err    error
+	}{
+		{FormatCSV, rows, "tick,address,balance,escrow,total\nrose,0xaa,1.5,2,3.5\nrose,0xbb,1,0,1\n", nil},
+		{FormatCSV, nil, "tick,address,balance,escrow,total\n", nil},
+		{FormatJSON, rows[1:], `{
+  "Block": 7,
+  "Rows": [
+    {
+      "Tick": "rose",
+      "Address": "0xbb",
+      "Balance": "1",
+      "Escrow": "0",
+      "Total": "1"
+    }
+  ]
+}
+`, nil},
+		{FormatJSON, nil, "{\n  \"Block\": 7,\n  \"Rows\": []\n}\n", nil},
+		{"xml", rows, "", ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "holders."+tt.format)
+			checksum, err := WriteFile(path, tt.format, 7, tt.rows)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("WriteFile err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				if _, err := os.Stat(path + ".sha256"); !os.IsNotExist(err) {
+					t.Fatal("checksum written for a failed export")
+				}
+				return
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("wrote %s, want %s", data, tt.want)
+			}
+			sum := sha256.Sum256(data)
+			if checksum != hex.EncodeToString(sum[:]) {
+				t.Fatalf("checksum = %s, want %x", checksum, sum)
+			}
+			line, err := os.ReadFile(path + ".sha256")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := checksum + "  holders." + tt.format + "\n"; string(line) != want {
+				t.Fatalf("checksum file = %q, want %q", line, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"rose-scriptions-open-indexer/core"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMaxLag       = 10
+	DefaultStallTimeout = 5 * time.Minute
+)
+
+// Monitor tracks the sync state reported by the chain fetcher and the committed blocks
+type Monitor struct {
+	// MaxLag is the largest lag behind the chain head that is still ready
+	MaxLag uint64
+	// StallTimeout is how long the indexer may lag without applying a block before it is considered stuck
+	StallTimeout time.Duration
+
+	lock          sync.Mutex
+	started       time.Time
+	lastApplied   time.Time
+	head          uint64
+	lastError     string
+	lastErrorTime time.Time
+	rpcError      string
+	rpcChecked    time.Time
+}
+
+// Status is the body of the health endpoints
+type Status struct {
+	Status         string
+	IndexedHeight  uint64
+	ChainHead      uint64
+	Lag            uint64
+	LastAppliedAt  *time.Time `json:",omitempty"`
+	SinceLastApply string     `json:",omitempty"`
+	LastError      string     `json:",omitempty"`
+	LastErrorAt    *time.Time `json:",omitempty"`
+	RPCReachable   bool
+	RPCError       string     `json:",omitempty"`
+	RPCCheckedAt   *time.Time `json:",omitempty"`
+	MaxLag         uint64
+	StallTimeout   string
+	Reasons        []string `json:",omitempty"`
+}
+
+func NewMonitor(maxLag uint64, stallTimeout time.Duration) *Monitor {
+	m := &Monitor{
+		MaxLag:       maxLag,
+		StallTimeout: stallTimeout,
+		started:      time.Now(),
+	}
+	core.OnBlockCommitted(m.blockCommitted)
+	return m
+}
+
+func (m *Monitor) blockCommitted(*core.BlockResult) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.lastApplied = time.Now()
+}
+
+// RecordHead records a successful chain head query
+func (m *Monitor) RecordHead(head uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.head = head
+	m.rpcError = ""
+	m.rpcChecked = time.Now()
+}
+
+// RecordRPCError records a failed chain head query
+func (m *Monitor) RecordRPCError(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.rpcError = err.Error()
+	m.rpcChecked = time.Now()
+	m.recordError(err)
+}
+
+// RecordError records a failure fetching or applying a block
+func (m *Monitor) RecordError(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.recordError(err)
+}
+
+func (m *Monitor) recordError(err error) {
+	m.lastError = err.Error()
+	m.lastErrorTime = time.Now()
+}
+
+// Check returns the current status, live reports whether the indexer makes progress
+// and ready whether it is live, reachable and within MaxLag of the chain head.
+func (m *Monitor) Check() (status *Status, live bool, ready bool) {
+	indexed := core.QueryLatestBlockNumber()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	status = &Status{
+		IndexedHeight: indexed,
+		ChainHead:     m.head,
+		LastError:     m.lastError,
+		RPCReachable:  !m.rpcChecked.IsZero() && m.rpcError == "",
+		RPCError:      m.rpcError,
+		MaxLag:        m.MaxLag,
+		StallTimeout:  m.StallTimeout.String(),
+	}
+	if m.head > indexed {
+		status.Lag = m.head - indexed
+	}
+	if !m.lastApplied.IsZero() {
+		lastApplied := m.lastApplied
+		status.LastAppliedAt = &lastApplied
+		status.SinceLastApply = now.Sub(lastApplied).Round(time.Second).String()
+	}
+	if !m.lastErrorTime.IsZero() {
+		lastErrorTime := m.lastErrorTime
+		status.LastErrorAt = &lastErrorTime
+	}
+	if !m.rpcChecked.IsZero() {
+		rpcChecked := m.rpcChecked
+		status.RPCCheckedAt = &rpcChecked
+	}
+
+	// progress is measured from the start until the first block is applied
+	lastProgress := m.lastApplied
+	if lastProgress.IsZero() {
+		lastProgress = m.started
+	}
+	live = true
+	if status.Lag > 0 && now.Sub(lastProgress) > m.StallTimeout {
+		live = false
+		status.Reasons = append(status.Reasons, "no block applied within the stall timeout")
+	}
+	if !m.rpcChecked.IsZero() && m.rpcError == "" && now.Sub(m.rpcChecked) > m.StallTimeout {
+		live = false
+		status.Reasons = append(status.Reasons, "chain head not queried within the stall timeout")
+	}
+
+	ready = live
+	if !status.RPCReachable {
+		ready = false
+		status.Reasons = append(status.Reasons, "chain rpc unreachable")
+	}
+	if status.Lag > m.MaxLag {
+		ready = false
+		status.Reasons = append(status.Reasons, "lag exceeds max lag")
+	}
+
+	switch {
+	case ready:
+		status.Status = "ok"
+	case live:
+		status.Status = "syncing"
+	default:
+		status.Status = "stuck"
+	}
+	return status, live, ready
+}
+
+// ServeLiveness answers 200 while the indexer makes progress, 503 when it is stuck
+func (m *Monitor) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	status, live, _ := m.Check()
+	writeStatus(w, status, live)
+}
+
+// ServeReadiness answers 200 when the indexer is within MaxLag of a reachable chain head, 503 otherwise
+func (m *Monitor) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	status, _, ready := m.Check()
+	writeStatus(w, status, ready)
+}
+
+func writeStatus(w http.ResponseWriter, status *Status, ok bool) {
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logrus.Warnf("write health status err: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"errors"
+	"rose-scriptions-open-indexer/core"
+	"testing"
+	"time"
+)
+
+func TestMonitorCheck(t *testing.T) {
+	indexed := core.QueryLatestBlockNumber()
+	ago := func(d time.Duration) time.Time { return time.Now().Add(-d) }
+	tests := []struct {
+		name        string
+		lag         uint64
+		started     time.Time
+		lastApplied time.Time
+		rpcChecked  time.Time
+		rpcError    string
+		status      string
+		live        bool
+		ready       bool
+	}{
+		{"at head", 0, ago(time.Hour), ago(time.Second), ago(time.Second), "", "ok", true, true},
+		{"within max lag", 10, ago(time.Hour), ago(time.Second), ago(time.Second), "", "ok", true, true},
+		{"lag exceeds max lag", 11, ago(time.Hour), ago(time.Second), ago(time.Second), "", "syncing", true, false},
+		{"at head without recent blocks", 0, ago(time.Hour), ago(time.Hour), ago(time.Second), "", "ok", true, true},
+		{"lag without recent blocks", 1, ago(time.Hour), ago(time.Hour), ago(time.Second), "", "stuck", false, false},
+		{"lag since start", 1, ago(time.Hour), time.Time{}, ago(time.Second), "", "stuck", false, false},
+		{"lag just started", 1, ago(time.Second), time.Time{}, ago(time.Second), "", "ok", true, true},
+		{"head not queried", 0, ago(time.Hour), ago(time.Second), time.Time{}, "", "syncing", true, false},
+		{"head query stalled", 0, ago(time.Hour), ago(time.Second), ago(time.Hour), "", "stuck", false, false},
+		{"rpc unreachable", 0, ago(time.Hour), ago(time.Second), ago(time.Hour), "dial tcp: refused", "syncing", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Monitor{
+				MaxLag:       10,
+				StallTimeout: time.Minute,
+				started:      tt.started,
+				lastApplied:  tt.lastApplied,
+				head:         indexed + tt.lag,
+				rpcError:     tt.rpcError,
+				rpcChecked:   tt.rpcChecked,
+			}
+			status, live, ready := m.Check()
+			if status.Status != tt.status || live != tt.live || ready != tt.ready {
+				t.Fatalf("Check = %s, live %v, ready %v, want %s, live %v, ready %v (%v)", status.Status, live, ready, tt.status, tt.live, tt.ready, status.Reasons)
+			}
+			if status.Lag != tt.lag || status.IndexedHeight != indexed {
+				t.Fatalf("lag = %d at %d, want %d at %d", status.Lag, status.IndexedHeight, tt.lag, indexed)
+			}
+		})
+	}
+}
+
+func TestMonitorRecordHead(t *testing.T) {
+	m := &Monitor{MaxLag: DefaultMaxLag, StallTimeout: DefaultStallTimeout, started: time.Now()}
+	m.RecordRPCError(errors.New("dial tcp: refused"))
+	if status, _, ready := m.Check(); ready || status.RPCReachable || status.LastError == "" {
+		t.Fatalf("status after an rpc error = %+v", status)
+	}
+	m.RecordHead(core.QueryLatestBlockNumber())
+	if status, _, ready := m.Check(); !ready || !status.RPCReachable || status.LastError == "" {
+		t.Fatalf("status after a head query = %+v", status)
+	}
+}
@@ -0,0 +1,119 @@
+// Package merkle implements the binary sha256 tree of the indexer state commitments.
+// It has no dependencies on the indexer so light clients can vendor it alone.
+//
+// Leaves are hashed as sha256(0x00 || data) and nodes as sha256(0x01 || left || right),
+// an odd node at the end of a level is carried up unchanged. The root of no leaves is all zeros.
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+type Hash [32]byte
+
+var EmptyRoot Hash
+
+var ErrInvalidHash = errors.New("invalid hash")
+
+func (h Hash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// ParseHash parses a 0x prefixed hex hash
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != 66 || s[:2] != "0x" {
+		return h, ErrInvalidHash
+	}
+	if _, err := hex.Decode(h[:], []byte(s[2:])); err != nil {
+		return h, ErrInvalidHash
+	}
+	return h, nil
+}
+
+// Encode concatenates length prefixed fields, the leaf data of the state entries
+func Encode(fields ...string) []byte {
+	var buf []byte
+	var size [binary.MaxVarintLen64]byte
+	for _, field := range fields {
+		n := binary.PutUvarint(size[:], uint64(len(field)))
+		buf = append(buf, size[:n]...)
+		buf = append(buf, field...)
+	}
+	return buf
+}
+
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	var res Hash
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
+func NodeHash(left Hash, right Hash) Hash {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left[:])
+	h.Write(right[:])
+	var res Hash
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
+// Root returns the root of the leaf hashes
+func Root(leaves []Hash) Hash {
+	if len(leaves) == 0 {
+		return EmptyRoot
+	}
+	level := append([]Hash(nil), leaves...)
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+func nextLevel(level []Hash) []Hash {
+	next := make([]Hash, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 < len(level) {
+			next = append(next, NodeHash(level[i], level[i+1]))
+		} else {
+			next = append(next, level[i])
+		}
+	}
+	return next
+}
+
+// The leaves of the state commitment. The balance tree of a tick has a BalanceLeaf per address with
+// a non-zero balance sorted by address, the balances tree a TickLeaf per tick with holders sorted by tick.
+
+func BalanceLeaf(address string, balance string) Hash {
+	return LeafHash(Encode(address, balance))
+}
+
+func TickLeaf(tick string, balancesRoot Hash) Hash {
+	return LeafHash(Encode(tick, string(balancesRoot[:])))
+}
+
+// StateRoot combines the sub-roots, it is the root of a tree with them as leaves
+func StateRoot(tokensRoot Hash, balancesRoot Hash, listsRoot Hash) Hash {
+	return Root([]Hash{tokensRoot, balancesRoot, listsRoot})
+}
@@ -0,0 +1,59 @@
+package merkle
+
+import (
+	"errors"
+	"testing"
+)
+
+func leaves(n int) []Hash {
+	res := make([]Hash, n)
+	for i := range res {
+		res[i] = LeafHash([]byte{byte(i)})
+	}
+	return res
+}
+
+func TestRoot(t *testing.T) {
+	l := leaves(5)
+	tests := []struct {
+		name   string
+		leaves []Hash
+		root   Hash
+	}{
+		{"empty", nil, EmptyRoot},
+		{"one", l[:1], l[0]},
+		{"two", l[:2], NodeHash(l[0], l[1])},
+		{"three carries the last up", l[:3], NodeHash(NodeHash(l[0], l[1]), l[2])},
+		{"five", l, NodeHash(NodeHash(NodeHash(l[0], l[1]), NodeHash(l[2], l[3])), l[4])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Root(tt.leaves); got != tt.root {
+				t.Fatalf("Root = %s, want %s", got, tt.root)
+			}
+		})
+	}
+}
+
+func TestLeafAndNodeHashesDiffer(t *testing.T) {
+	a, b := LeafHash([]byte("a")), LeafHash([]byte("b"))
+	if LeafHash(append(append([]byte(nil), a[:]...), b[:]...)) == NodeHash(a, b) {
+		t.Fatal("a leaf of two hashes collides with their node")
+	}
+	if BalanceLeaf("ab", "c") == BalanceLeaf("a", "bc") {
+		t.Fatal("fields are not length prefixed")
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	h := LeafHash([]byte("x"))
+	parsed, err := ParseHash(h.String())
+	if err != nil || parsed != h {
+		t.Fatalf("ParseHash(%s) = %s, %v", h, parsed, err)
+	}
+	for _, s := range []string{"", h.String()[2:], h.String()[:65], "0x" + string(make([]byte, 64))} {
+		if _, err := ParseHash(s); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("ParseHash(%q) err = %v, want ErrInvalidHash", s, err)
+		}
+	}
+}
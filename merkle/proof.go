@@ -0,0 +1,66 @@
+package merkle
+
+import "errors"
+
+var ErrProofMismatch = errors.New("proof does not match the state root")
+
+// Step is a sibling on the path from a leaf to the root, Left is set when it is the left node
+type Step struct {
+	Hash Hash
+	Left bool
+}
+
+// Prove returns the path from the leaf at index to the root of leaves.
+// Levels where the node is carried up have no step.
+func Prove(leaves []Hash, index int) []Step {
+	path := make([]Step, 0)
+	level := leaves
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			path = append(path, Step{Hash: level[sibling], Left: sibling < index})
+		}
+		level = nextLevel(level)
+		index /= 2
+	}
+	return path
+}
+
+// RootFromPath returns the root leaf leads to with path
+func RootFromPath(leaf Hash, path []Step) Hash {
+	node := leaf
+	for _, step := range path {
+		if step.Left {
+			node = NodeHash(step.Hash, node)
+		} else {
+			node = NodeHash(node, step.Hash)
+		}
+	}
+	return node
+}
+
+// BalanceProof shows that Address holds Balance of Tick in the state committed to after Block.
+// Tick is the state key of the token, lowercased or, for deploys from the strict parsing height on,
+// NFKC case-folded. Address is lowercased and Balance a decimal string, as in the leaves.
+type BalanceProof struct {
+	Block   uint64
+	Tick    string
+	Address string
+	Balance string
+	// BalancePath leads from the balance leaf to the root of the tick, TickPath from the tick leaf to BalancesRoot
+	BalancePath []Step
+	TickPath    []Step
+	TokensRoot  Hash
+	ListsRoot   Hash
+	StateRoot   Hash
+}
+
+// Verify checks the proof against a state root obtained elsewhere, the StateRoot of the proof is not trusted
+func (p *BalanceProof) Verify(stateRoot Hash) error {
+	tickRoot := RootFromPath(BalanceLeaf(p.Address, p.Balance), p.BalancePath)
+	balancesRoot := RootFromPath(TickLeaf(p.Tick, tickRoot), p.TickPath)
+	if StateRoot(p.TokensRoot, balancesRoot, p.ListsRoot) != stateRoot {
+		return ErrProofMismatch
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestProve(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		l := leaves(n)
+		root := Root(l)
+		for i := range l {
+			if got := RootFromPath(l[i], Prove(l, i)); got != root {
+				t.Fatalf("%d leaves, leaf %d leads to %s, want %s", n, i, got, root)
+			}
+		}
+	}
+}
+
+// balanceProof builds the proof of the balance at index in a state of odd sized trees
+func balanceProof(index int) (*BalanceProof, Hash) {
+	addresses := []string{"0xaa", "0xbb", "0xcc", "0xdd", "0xee"}
+	balanceLeaves := make([]Hash, len(addresses))
+	for i, address := range addresses {
+		balanceLeaves[i] = BalanceLeaf(address, fmt.Sprint(i+1))
+	}
+	ticks := []string{"gem", "rose", "star"}
+	tickLeaves := make([]Hash, len(ticks))
+	for i, tick := range ticks {
+		tickRoot := Root(leaves(i + 1))
+		if tick == "rose" {
+			tickRoot = Root(balanceLeaves)
+		}
+		tickLeaves[i] = TickLeaf(tick, tickRoot)
+	}
+	tokensRoot, listsRoot := LeafHash([]byte("tokens")), LeafHash([]byte("lists"))
+	stateRoot := StateRoot(tokensRoot, Root(tickLeaves), listsRoot)
+	return &BalanceProof{
+		Block:       1,
+		Tick:        "rose",
+		Address:     addresses[index],
+		Balance:     fmt.Sprint(index + 1),
+		BalancePath: Prove(balanceLeaves, index),
+		TickPath:    Prove(tickLeaves, 1),
+		TokensRoot:  tokensRoot,
+		ListsRoot:   listsRoot,
+		StateRoot:   stateRoot,
+	}, stateRoot
+}
+
+func TestBalanceProofVerify(t *testing.T) {
+	for index := 0; index < 5; index++ {
+		proof, stateRoot := balanceProof(index)
+		if err := proof.Verify(stateRoot); err != nil {
+			t.Fatalf("proof of balance %d: %v", index, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(p *BalanceProof, stateRoot *Hash)
+	}{
+		{"balance", func(p *BalanceProof, _ *Hash) { p.Balance = "100" }},
+		{"address", func(p *BalanceProof, _ *Hash) { p.Address = "0xbb" }},
+		{"tick", func(p *BalanceProof, _ *Hash) { p.Tick = "gem" }},
+		{"dropped step", func(p *BalanceProof, _ *Hash) { p.BalancePath = p.BalancePath[1:] }},
+		{"flipped side", func(p *BalanceProof, _ *Hash) { p.TickPath[0].Left = !p.TickPath[0].Left }},
+		{"lists root", func(p *BalanceProof, _ *Hash) { p.ListsRoot = Hash{} }},
+		{"proof's own root is not trusted", func(p *BalanceProof, stateRoot *Hash) { *stateRoot = Hash{1}; p.StateRoot = *stateRoot }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the last balance of the odd tree is carried up a level
+			proof, stateRoot := balanceProof(4)
+			tt.tamper(proof, &stateRoot)
+			if err := proof.Verify(stateRoot); !errors.Is(err, ErrProofMismatch) {
+				t.Fatalf("Verify = %v, want ErrProofMismatch", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rose_indexer"
+
+var (
+	IndexedHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexed_height",
+		Help:      "Number of the last applied block.",
+	})
+	ChainHead = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_head",
+		Help:      "Latest block number reported by the chain rpc.",
+	})
+	HeadLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_head_lag",
+		Help:      "Blocks between the chain head and the indexed height.",
+	})
+	BlockProcessing = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "block_processing_seconds",
+		Help:      "Time spent applying a block.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	})
+	RPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Chain rpc calls by method and result.",
+	}, []string{"method", "result"})
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Chain rpc call latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	Inscriptions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inscriptions_total",
+		Help:      "Inscriptions seen.",
+	})
+	Operations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rrc20_operations_total",
+		Help:      "rrc-20 operations by operation and valid code.",
+	}, []string{"operation", "valid"})
+	ActiveListings = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_listings",
+		Help:      "Listings not yet exchanged or cancelled.",
+	})
+	Tokens = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tokens",
+		Help:      "Deployed rrc-20 tokens.",
+	})
+	InvariantViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invariant_violations_total",
+		Help:      "Accounting invariant violations by invariant.",
+	}, []string{"invariant"})
+)
+
+var (
+	heightLock sync.Mutex
+	indexed    uint64
+	head       uint64
+)
+
+// SetIndexedHeight updates the indexed height and the lag behind the chain head
+func SetIndexedHeight(height uint64) {
+	heightLock.Lock()
+	defer heightLock.Unlock()
+
+	indexed = height
+	IndexedHeight.Set(float64(height))
+	updateLag()
+}
+
+// SetChainHead updates the chain head and the lag of the indexed height behind it
+func SetChainHead(height uint64) {
+	heightLock.Lock()
+	defer heightLock.Unlock()
+
+	head = height
+	ChainHead.Set(float64(height))
+	updateLag()
+}
+
+func updateLag() {
+	if head > indexed {
+		HeadLag.Set(float64(head - indexed))
+	} else {
+		HeadLag.Set(0)
+	}
+}
+
+// ObserveRPC records a chain rpc call started at start
+func ObserveRPC(method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	RPCRequests.WithLabelValues(method, result).Inc()
+	RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveOperation counts an rrc-20 operation with its valid code
+func ObserveOperation(operation string, valid int8) {
+	Operations.WithLabelValues(operation, strconv.Itoa(int(valid))).Inc()
+}
+
+// Handler serves the default registry in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
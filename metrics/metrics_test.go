@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHeadLag(t *testing.T) {
+	tests := []struct {
+		name          string
+		indexed, head uint64
+		lag           float64
+	}{
+		{"behind", 90, 100, 10},
+		{"at head", 100, 100, 0},
+		{"head not reported yet", 100, 0, 0},
+		{"ahead of a stale head", 105, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetChainHead(tt.head)
+			SetIndexedHeight(tt.indexed)
+			if got := testutil.ToFloat64(HeadLag); got != tt.lag {
+				t.Fatalf("lag = %v, want %v", got, tt.lag)
+			}
+			if testutil.ToFloat64(IndexedHeight) != float64(tt.indexed) || testutil.ToFloat64(ChainHead) != float64(tt.head) {
+				t.Fatal("heights not exported")
+			}
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	ObserveRPC("eth_blockNumber", time.Now(), nil)
+	ObserveRPC("eth_blockNumber", time.Now(), errors.New("timeout"))
+	ObserveRPC("eth_blockNumber", time.Now(), errors.New("timeout"))
+	if ok, failed := testutil.ToFloat64(RPCRequests.WithLabelValues("eth_blockNumber", "ok")), testutil.ToFloat64(RPCRequests.WithLabelValues("eth_blockNumber", "error")); ok != 1 || failed != 2 {
+		t.Fatalf("rpc requests = %v ok, %v error", ok, failed)
+	}
+	ObserveOperation("transfer", -3)
+	if got := testutil.ToFloat64(Operations.WithLabelValues("transfer", "-3")); got != 1 {
+		t.Fatalf("operations = %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if body := w.Body.String(); !strings.Contains(body, `rose_indexer_rpc_requests_total{method="eth_blockNumber",result="error"} 2`) {
+		t.Fatalf("metrics missing the rpc requests:\n%s", body)
+	}
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/rpc/pb"
+)
+
+func decimalString(d *model.DDecimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func toPbToken(token *model.Token) *pb.Token {
+	return &pb.Token{
+		Tick:           token.Tick,
+		Number:         token.Number,
+		Precision:      int32(token.Precision),
+		Max:            decimalString(token.Max),
+		Limit:          decimalString(token.Limit),
+		Minted:         decimalString(token.Minted),
+		Progress:       token.Progress,
+		Holders:        token.Holders,
+		Trxs:           token.Trxs,
+		CreatedAt:      token.CreatedAt,
+		CompletedAt:    token.CompletedAt,
+		DeployAddress:  token.DeployAddress,
+		DeployHash:     token.DeployHash,
+		Confusable:     token.Confusable,
+		ConfusableWith: token.ConfusableWith,
+	}
+}
+
+func toPbRecord(rrc20 *model.RRC20) *pb.RRC20 {
+	return &pb.RRC20{
+		Number:    rrc20.Number,
+		Hash:      rrc20.Hash,
+		Tick:      rrc20.Tick,
+		Operation: string(rrc20.Operation),
+		From:      rrc20.From,
+		To:        rrc20.To,
+		Precision: int32(rrc20.Precision),
+		Max:       decimalString(rrc20.Max),
+		Limit:     decimalString(rrc20.Limit),
+		Timestamp: rrc20.Timestamp,
+		Amount:    decimalString(rrc20.Amount),
+		Valid:     int32(rrc20.Valid),
+	}
+}
+
+func toPbBalance(balance *core.Balance) *pb.Balance {
+	return &pb.Balance{
+		Tick:   balance.Tick,
+		Amount: decimalString(balance.Amount),
+	}
+}
+
+func toPbBalanceChange(change *model.BalanceChange) *pb.BalanceChange {
+	return &pb.BalanceChange{
+		Block:   change.Block,
+		TxHash:  change.TxHash,
+		Address: change.Address,
+		Tick:    change.Tick,
+		Delta:   decimalString(change.Delta),
+		Balance: decimalString(change.Balance),
+	}
+}
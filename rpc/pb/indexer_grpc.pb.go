@@ -0,0 +1,316 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: indexer.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	IndexerService_GetToken_FullMethodName                = "/indexer.v1.IndexerService/GetToken"
+	IndexerService_GetBalance_FullMethodName              = "/indexer.v1.IndexerService/GetBalance"
+	IndexerService_GetOperation_FullMethodName            = "/indexer.v1.IndexerService/GetOperation"
+	IndexerService_SubscribeRecords_FullMethodName        = "/indexer.v1.IndexerService/SubscribeRecords"
+	IndexerService_SubscribeBalanceChanges_FullMethodName = "/indexer.v1.IndexerService/SubscribeBalanceChanges"
+)
+
+// IndexerServiceClient is the client API for IndexerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IndexerServiceClient interface {
+	GetToken(ctx context.Context, in *GetTokenRequest, opts ...grpc.CallOption) (*Token, error)
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*RRC20, error)
+	// SubscribeRecords streams every rrc-20 operation of each committed block.
+	SubscribeRecords(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (IndexerService_SubscribeRecordsClient, error)
+	// SubscribeBalanceChanges streams every balance change of each committed block.
+	SubscribeBalanceChanges(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (IndexerService_SubscribeBalanceChangesClient, error)
+}
+
+type indexerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIndexerServiceClient(cc grpc.ClientConnInterface) IndexerServiceClient {
+	return &indexerServiceClient{cc}
+}
+
+func (c *indexerServiceClient) GetToken(ctx context.Context, in *GetTokenRequest, opts ...grpc.CallOption) (*Token, error) {
+	out := new(Token)
+	err := c.cc.Invoke(ctx, IndexerService_GetToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, IndexerService_GetBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*RRC20, error) {
+	out := new(RRC20)
+	err := c.cc.Invoke(ctx, IndexerService_GetOperation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexerServiceClient) SubscribeRecords(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (IndexerService_SubscribeRecordsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexerService_ServiceDesc.Streams[0], IndexerService_SubscribeRecords_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexerServiceSubscribeRecordsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexerService_SubscribeRecordsClient interface {
+	Recv() (*RRC20, error)
+	grpc.ClientStream
+}
+
+type indexerServiceSubscribeRecordsClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexerServiceSubscribeRecordsClient) Recv() (*RRC20, error) {
+	m := new(RRC20)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *indexerServiceClient) SubscribeBalanceChanges(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (IndexerService_SubscribeBalanceChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexerService_ServiceDesc.Streams[1], IndexerService_SubscribeBalanceChanges_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexerServiceSubscribeBalanceChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexerService_SubscribeBalanceChangesClient interface {
+	Recv() (*BalanceChange, error)
+	grpc.ClientStream
+}
+
+type indexerServiceSubscribeBalanceChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexerServiceSubscribeBalanceChangesClient) Recv() (*BalanceChange, error) {
+	m := new(BalanceChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IndexerServiceServer is the server API for IndexerService service.
+// All implementations must embed UnimplementedIndexerServiceServer
+// for forward compatibility
+type IndexerServiceServer interface {
+	GetToken(context.Context, *GetTokenRequest) (*Token, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	GetOperation(context.Context, *GetOperationRequest) (*RRC20, error)
+	// SubscribeRecords streams every rrc-20 operation of each committed block.
+	SubscribeRecords(*SubscribeRequest, IndexerService_SubscribeRecordsServer) error
+	// SubscribeBalanceChanges streams every balance change of each committed block.
+	SubscribeBalanceChanges(*SubscribeRequest, IndexerService_SubscribeBalanceChangesServer) error
+	mustEmbedUnimplementedIndexerServiceServer()
+}
+
+// UnimplementedIndexerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedIndexerServiceServer struct {
+}
+
+func (UnimplementedIndexerServiceServer) GetToken(context.Context, *GetTokenRequest) (*Token, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetToken not implemented")
+}
+func (UnimplementedIndexerServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedIndexerServiceServer) GetOperation(context.Context, *GetOperationRequest) (*RRC20, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperation not implemented")
+}
+func (UnimplementedIndexerServiceServer) SubscribeRecords(*SubscribeRequest, IndexerService_SubscribeRecordsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeRecords not implemented")
+}
+func (UnimplementedIndexerServiceServer) SubscribeBalanceChanges(*SubscribeRequest, IndexerService_SubscribeBalanceChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeBalanceChanges not implemented")
+}
+func (UnimplementedIndexerServiceServer) mustEmbedUnimplementedIndexerServiceServer() {}
+
+// UnsafeIndexerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IndexerServiceServer will
+// result in compilation errors.
+type UnsafeIndexerServiceServer interface {
+	mustEmbedUnimplementedIndexerServiceServer()
+}
+
+func RegisterIndexerServiceServer(s grpc.ServiceRegistrar, srv IndexerServiceServer) {
+	s.RegisterService(&IndexerService_ServiceDesc, srv)
+}
+
+func _IndexerService_GetToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexerServiceServer).GetToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IndexerService_GetToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexerServiceServer).GetToken(ctx, req.(*GetTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexerService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexerServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IndexerService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexerServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexerService_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexerServiceServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IndexerService_GetOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexerServiceServer).GetOperation(ctx, req.(*GetOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexerService_SubscribeRecords_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexerServiceServer).SubscribeRecords(m, &indexerServiceSubscribeRecordsServer{stream})
+}
+
+type IndexerService_SubscribeRecordsServer interface {
+	Send(*RRC20) error
+	grpc.ServerStream
+}
+
+type indexerServiceSubscribeRecordsServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexerServiceSubscribeRecordsServer) Send(m *RRC20) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _IndexerService_SubscribeBalanceChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexerServiceServer).SubscribeBalanceChanges(m, &indexerServiceSubscribeBalanceChangesServer{stream})
+}
+
+type IndexerService_SubscribeBalanceChangesServer interface {
+	Send(*BalanceChange) error
+	grpc.ServerStream
+}
+
+type indexerServiceSubscribeBalanceChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexerServiceSubscribeBalanceChangesServer) Send(m *BalanceChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// IndexerService_ServiceDesc is the grpc.ServiceDesc for IndexerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IndexerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "indexer.v1.IndexerService",
+	HandlerType: (*IndexerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetToken",
+			Handler:    _IndexerService_GetToken_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _IndexerService_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _IndexerService_GetOperation_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeRecords",
+			Handler:       _IndexerService_SubscribeRecords_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeBalanceChanges",
+			Handler:       _IndexerService_SubscribeBalanceChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "indexer.proto",
+}
@@ -0,0 +1,178 @@
+package rpc
+
+//go:generate protoc -I proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative indexer.proto
+
+import (
+	"context"
+	"net"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/rpc/pb"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriberBuffer is the number of blocks a stream may lag behind before it is dropped
+const subscriberBuffer = 64
+
+type Server struct {
+	pb.UnimplementedIndexerServiceServer
+
+	addr        string
+	lock        sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	results chan *core.BlockResult
+	// dropped is closed when the subscriber could not keep up
+	dropped chan struct{}
+}
+
+func NewServer(addr string) *Server {
+	s := &Server{
+		addr:        addr,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	core.OnBlockCommitted(s.publish)
+	return s
+}
+
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterIndexerServiceServer(grpcServer, s)
+
+	logrus.Infof("grpc server listening on %s", s.addr)
+	return grpcServer.Serve(listener)
+}
+
+func (s *Server) GetToken(ctx context.Context, req *pb.GetTokenRequest) (*pb.Token, error) {
+	token, ok := core.QueryToken(req.Tick)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "token %s not found", req.Tick)
+	}
+	return toPbToken(token), nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	res := &pb.GetBalanceResponse{}
+	for _, balance := range core.QueryBalances(req.Address) {
+		if req.Tick == "" || core.TickKey(balance.Tick) == core.TickKey(req.Tick) {
+			res.Balances = append(res.Balances, toPbBalance(balance))
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) GetOperation(ctx context.Context, req *pb.GetOperationRequest) (*pb.RRC20, error) {
+	rrc20, ok := core.QueryRecordByHash(req.Hash)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "operation %s not found", req.Hash)
+	}
+	return toPbRecord(rrc20), nil
+}
+
+func (s *Server) SubscribeRecords(req *pb.SubscribeRequest, stream pb.IndexerService_SubscribeRecordsServer) error {
+	return s.stream(stream.Context(), func(result *core.BlockResult) error {
+		for _, rrc20 := range result.Records {
+			if match(req, rrc20.Tick, rrc20.From, rrc20.To) {
+				if err := stream.Send(toPbRecord(rrc20)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Server) SubscribeBalanceChanges(req *pb.SubscribeRequest, stream pb.IndexerService_SubscribeBalanceChangesServer) error {
+	return s.stream(stream.Context(), func(result *core.BlockResult) error {
+		for _, change := range result.BalanceChanges {
+			if match(req, change.Tick, change.Address) {
+				if err := stream.Send(toPbBalanceChange(change)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// stream calls send with every committed block until the client goes away
+func (s *Server) stream(ctx context.Context, send func(*core.BlockResult) error) error {
+	sub := s.subscribe()
+	defer s.unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.dropped:
+			return status.Error(codes.ResourceExhausted, "subscriber too slow")
+		case result := <-sub.results:
+			if err := send(result); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) subscribe() *subscriber {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sub := &subscriber{
+		results: make(chan *core.BlockResult, subscriberBuffer),
+		dropped: make(chan struct{}),
+	}
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.subscribers, sub)
+}
+
+func (s *Server) publish(result *core.BlockResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for sub := range s.subscribers {
+		select {
+		case sub.results <- result:
+		default:
+			logrus.Warnf("drop grpc subscriber at block %d", result.Number)
+			delete(s.subscribers, sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+func match(req *pb.SubscribeRequest, tick string, addresses ...string) bool {
+	if req.Tick != "" && core.TickKey(req.Tick) != core.TickKey(tick) {
+		return false
+	}
+	if req.Address == "" {
+		return true
+	}
+	for _, address := range addresses {
+		if strings.EqualFold(address, req.Address) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"rose-scriptions-open-indexer/rpc/pb"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *pb.SubscribeRequest
+		ok   bool
+	}{
+		{"empty", &pb.SubscribeRequest{}, true},
+		{"tick in another case", &pb.SubscribeRequest{Tick: "Rose"}, true},
+		{"other tick", &pb.SubscribeRequest{Tick: "gem"}, false},
+		{"sender", &pb.SubscribeRequest{Address: "0xAA"}, true},
+		{"receiver", &pb.SubscribeRequest{Address: "0xbb"}, true},
+		{"other address", &pb.SubscribeRequest{Address: "0xcc"}, false},
+		{"tick and address", &pb.SubscribeRequest{Tick: "ROSE", Address: "0xbb"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := match(tt.req, "rose", "0xaa", "0xBB"); got != tt.ok {
+				t.Fatalf("match = %v, want %v", got, tt.ok)
+			}
+		})
+	}
+}
+
+// recordStream collects what the server sends
+type recordStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.RRC20
+}
+
+func (s *recordStream) Context() context.Context { return s.ctx }
+
+func (s *recordStream) Send(rrc20 *pb.RRC20) error {
+	s.sent <- rrc20
+	return nil
+}
+
+func TestSubscribeRecords(t *testing.T) {
+	s := &Server{subscribers: make(map[*subscriber]struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &recordStream{ctx: ctx, sent: make(chan *pb.RRC20, 4)}
+	done := make(chan error)
+	go func() {
+		done <- s.SubscribeRecords(&pb.SubscribeRequest{Tick: "rose"}, stream)
+	}()
+	for subscribed := false; !subscribed; {
+		s.lock.Lock()
+		subscribed = len(s.subscribers) == 1
+		s.lock.Unlock()
+	}
+
+	s.publish(&core.BlockResult{Number: 1, Records: []*model.RRC20{
+		{Hash: "0x01", Tick: "gem", Operation: model.RRC20OperationMint},
+		{Hash: "0x02", Tick: "ROSE", Operation: model.RRC20OperationMint},
+	}})
+	select {
+	case rrc20 := <-stream.sent:
+		if rrc20.Hash != "0x02" || rrc20.Amount != "" {
+			t.Fatalf("sent %v", rrc20)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("record not sent")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("SubscribeRecords = %v after the client went away", err)
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	s := &Server{subscribers: make(map[*subscriber]struct{})}
+	block := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.stream(context.Background(), func(*core.BlockResult) error {
+			<-block
+			return nil
+		})
+	}()
+	for subscribed := false; !subscribed; {
+		s.lock.Lock()
+		subscribed = len(s.subscribers) == 1
+		s.lock.Unlock()
+	}
+
+	// one block held by the stream, a full buffer and one more
+	for i := 0; i < subscriberBuffer+2; i++ {
+		s.publish(&core.BlockResult{Number: uint64(i)})
+	}
+	s.lock.Lock()
+	subscribers := len(s.subscribers)
+	s.lock.Unlock()
+	if subscribers != 0 {
+		t.Fatal("slow subscriber kept")
+	}
+	close(block)
+	if err := <-done; status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("stream = %v, want ResourceExhausted", err)
+	}
+}
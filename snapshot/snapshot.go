@@ -0,0 +1,316 @@
+// Package snapshot reads and writes portable state snapshots: a json header line followed by the gzip'd
+// json state, the header holds the sha256 of the compressed state so a snapshot is checked before it is used.
+// The state root of the header commits to the tokens, balances and listings, its ContentHash to the rest.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/merkle"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Format  = "rose-indexer-snapshot"
+	Version = 1
+
+	filePrefix = "snapshot-"
+	fileSuffix = ".snap"
+)
+
+var (
+	ErrFormat   = errors.New("not a snapshot")
+	ErrVersion  = errors.New("unsupported snapshot version")
+	ErrChecksum = errors.New("snapshot checksum mismatch")
+	ErrContent  = errors.New("snapshot content hash mismatch")
+)
+
+// Header describes the snapshot, it is readable without decompressing the state
+type Header struct {
+	Format       string
+	Version      int
+	StateVersion int
+	Block        uint64
+	// BlockHash and StateRoot are the ones of Block, empty when the state has no state root for it
+	BlockHash string
+	StateRoot merkle.Hash
+	// ContentHash covers the state the state root doesn't, see ContentHash. Unlike SHA256 it doesn't
+	// depend on the compression, two indexers at the same block write the same one.
+	ContentHash string `json:",omitempty"`
+	// Size and SHA256 are the length and hash of the compressed state following the header
+	Size   int64
+	SHA256 string
+}
+
+// NewHeader returns the header of state, without the checksum
+func NewHeader(state *core.State) (*Header, error) {
+	contentHash, err := ContentHash(state)
+	if err != nil {
+		return nil, err
+	}
+	header := &Header{
+		Format:       Format,
+		Version:      Version,
+		StateVersion: state.Version,
+		Block:        state.LatestBlockNumber,
+		ContentHash:  contentHash,
+	}
+	if n := len(state.BlockRoots); n > 0 && state.BlockRoots[n-1].Number == state.LatestBlockNumber {
+		header.BlockHash = state.BlockRoots[n-1].Hash
+		header.StateRoot = state.BlockRoots[n-1].StateRoot
+	}
+	return header, nil
+}
+
+// ContentHash returns the hex sha256 of the json encoding of the state the state root doesn't commit to:
+// the inscription counter, inscriptions, records, balances by owner, balance journal and block roots.
+// The encoding is deterministic, maps are encoded with sorted keys and ExportState sorts the journal.
+func ContentHash(state *core.State) (string, error) {
+	hash := sha256.New()
+	err := json.NewEncoder(hash).Encode(struct {
+		InscriptionNumber uint64
+		Inscriptions      interface{}
+		Records           interface{}
+		Balances          interface{}
+		BalanceChanges    interface{}
+		BlockRoots        interface{}
+	}{
+		InscriptionNumber: state.InscriptionNumber,
+		Inscriptions:      state.Inscriptions,
+		Records:           state.Records,
+		Balances:          state.Balances,
+		BalanceChanges:    state.BalanceChanges,
+		BlockRoots:        state.BlockRoots,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write saves state to path. The state is compressed to a temporary file first
+// since the header holding its checksum comes before it.
+func Write(path string, state *core.State) (*Header, error) {
+	dir := filepath.Dir(path)
+	payload, err := os.CreateTemp(dir, filePrefix+"*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(payload.Name())
+	defer payload.Close()
+
+	hash := sha256.New()
+	counter := &countWriter{}
+	writer := gzip.NewWriter(io.MultiWriter(payload, hash, counter))
+	if err := json.NewEncoder(writer).Encode(state); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	header, err := NewHeader(state)
+	if err != nil {
+		return nil, err
+	}
+	header.Size = counter.n
+	header.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	out, err := os.CreateTemp(dir, filePrefix+"*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	if err := json.NewEncoder(out).Encode(header); err != nil {
+		return nil, err
+	}
+	if _, err := payload.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(out, payload); err != nil {
+		return nil, err
+	}
+	if err := out.Sync(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(out.Name(), path); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func readHeader(reader *bufio.Reader) (*Header, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, ErrFormat
+	}
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil || header.Format != Format {
+		return nil, ErrFormat
+	}
+	if header.Version != Version {
+		return nil, fmt.Errorf("%w %d", ErrVersion, header.Version)
+	}
+	if header.StateVersion < 1 || header.StateVersion > core.StateVersion {
+		return nil, fmt.Errorf("%w, state version %d", ErrVersion, header.StateVersion)
+	}
+	return &header, nil
+}
+
+// ReadHeader reads the header of the snapshot at path
+func ReadHeader(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readHeader(bufio.NewReader(f))
+}
+
+// Read loads the snapshot at path, the state is returned only if its checksum matches
+func Read(path string) (*Header, *core.State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Decode reads a snapshot from r, the state is returned only if its checksum and content hash match
+func Decode(r io.Reader) (*Header, *core.State, error) {
+	reader := bufio.NewReader(r)
+	header, err := readHeader(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash := sha256.New()
+	counter := &countWriter{}
+	payload := io.TeeReader(io.LimitReader(reader, header.Size), io.MultiWriter(hash, counter))
+	gz, err := gzip.NewReader(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	var state core.State
+	if err := json.NewDecoder(gz).Decode(&state); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	// the rest of the gzip stream is part of the checksum
+	if _, err := io.Copy(io.Discard, payload); err != nil {
+		return nil, nil, err
+	}
+	if counter.n != header.Size || hex.EncodeToString(hash.Sum(nil)) != header.SHA256 {
+		return nil, nil, ErrChecksum
+	}
+	if state.LatestBlockNumber != header.Block {
+		return nil, nil, fmt.Errorf("snapshot header is for block %d, state for %d", header.Block, state.LatestBlockNumber)
+	}
+	contentHash, err := ContentHash(&state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if contentHash != header.ContentHash {
+		return nil, nil, ErrContent
+	}
+	// the roots of a snapshot are the ones of its writer
+	state.ImportedTo = header.Block
+	return header, &state, nil
+}
+
+// Dir keeps periodic snapshots named after their block
+type Dir struct {
+	dir string
+	// keep is the number of snapshots kept after a write, 0 keeps all
+	keep int
+}
+
+func NewDir(dir string, keep int) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Dir{dir: dir, keep: keep}, nil
+}
+
+func (d *Dir) Path(block uint64) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%s%020d%s", filePrefix, block, fileSuffix))
+}
+
+// Blocks lists the blocks of the snapshots in ascending order
+func (d *Dir) Blocks() ([]uint64, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		block, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i] < blocks[j]
+	})
+	return blocks, nil
+}
+
+// Latest returns the path of the most recent snapshot, "" if there is none
+func (d *Dir) Latest() (string, error) {
+	blocks, err := d.Blocks()
+	if err != nil || len(blocks) == 0 {
+		return "", err
+	}
+	return d.Path(blocks[len(blocks)-1]), nil
+}
+
+// Write saves the snapshot of state and drops the oldest ones beyond keep
+func (d *Dir) Write(state *core.State) (*Header, error) {
+	header, err := Write(d.Path(state.LatestBlockNumber), state)
+	if err != nil {
+		return nil, err
+	}
+	if d.keep <= 0 {
+		return header, nil
+	}
+	blocks, err := d.Blocks()
+	if err != nil {
+		return nil, err
+	}
+	for len(blocks) > d.keep {
+		if err := os.Remove(d.Path(blocks[0])); err != nil {
+			return nil, err
+		}
+		blocks = blocks[1:]
+	}
+	return header, nil
+}
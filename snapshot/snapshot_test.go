@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"strings"
+	"testing"
+)
+
+func decimal(t *testing.T, s string) *model.DDecimal {
+	t.Helper()
+	d, _, err := model.NewDecimalFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func testState(t *testing.T) *core.State {
+	const owner = "0x00000000000000000000000000000000000000ab"
+	return &core.State{
+		Version:           core.StateVersion,
+		LatestBlockNumber: 10,
+		InscriptionNumber: 1,
+		Inscriptions:      []*model.Inscription{{Hash: "0x01", Number: 1, From: owner, Block: 9, Content: `{"p":"rrc-20"}`}},
+		Records: []*model.RRC20{{
+			Number: 1, Hash: "0x01", Tick: "rose", Operation: model.RRC20OperationMint, To: owner, Amount: decimal(t, "1.5"),
+		}},
+		Tokens:         map[string]*model.Token{"rose": {Tick: "rose", Max: decimal(t, "10"), Limit: decimal(t, "5"), Minted: decimal(t, "1.5")}},
+		TokenHolders:   map[string]map[string]*model.DDecimal{"rose": {owner: decimal(t, "1.5")}},
+		Balances:       map[string]map[string]*model.DDecimal{owner: {"rose": decimal(t, "1.5")}},
+		BalanceChanges: []*model.BalanceChange{{Block: 9, TxHash: "0x01", Address: owner, Tick: "rose", Delta: decimal(t, "1.5"), Balance: decimal(t, "1.5")}},
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.snap")
+	written, err := Write(path, testState(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Version != Version || written.ContentHash == "" {
+		t.Fatalf("header = %+v", written)
+	}
+	header, state, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.ContentHash != written.ContentHash {
+		t.Fatalf("content hash = %s, want %s", header.ContentHash, written.ContentHash)
+	}
+	if hash, _ := ContentHash(state); hash != written.ContentHash {
+		t.Fatalf("content hash of the read state = %s, want %s", hash, written.ContentHash)
+	}
+
+	tampered := testState(t)
+	tampered.Records[0].Amount = decimal(t, "2")
+	if hash, _ := ContentHash(tampered); hash == written.ContentHash {
+		t.Fatal("content hash doesn't cover the records")
+	}
+}
+
+func TestDecodeContentMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.snap")
+	if _, err := Write(path, testState(t)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the checksum still matches the payload, only the header's content hash is wrong
+	lines := strings.SplitN(string(data), "\n", 2)
+	header, _, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := strings.Replace(lines[0], header.ContentHash, strings.Repeat("0", 64), 1)
+	if _, _, err := Decode(strings.NewReader(forged + "\n" + lines[1])); !errors.Is(err, ErrContent) {
+		t.Fatalf("Decode err = %v, want ErrContent", err)
+	}
+}
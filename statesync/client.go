@@ -0,0 +1,128 @@
+package statesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	requestTimeout = 5 * time.Minute
+	chunkAttempts  = 5
+)
+
+var (
+	ErrNoSnapshot    = errors.New("source has no snapshot")
+	ErrChunkChecksum = errors.New("chunk checksum mismatch")
+	ErrGone          = errors.New("snapshot no longer available")
+)
+
+// Client downloads the latest snapshot of another instance
+type Client struct {
+	base   string
+	client *http.Client
+}
+
+func NewClient(base string) *Client {
+	return &Client{
+		base:   strings.TrimSuffix(base, "/"),
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (c *Client) Manifest() (*Manifest, error) {
+	resp, err := c.client.Get(c.base + routePrefix + "manifest")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoSnapshot
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("manifest: %s", resp.Status)
+	}
+	var res struct {
+		Data *Manifest `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	if res.Data == nil || res.Data.Header == nil {
+		return nil, errors.New("manifest: empty")
+	}
+	return res.Data, nil
+}
+
+// Chunk downloads a chunk and checks it against the manifest
+func (c *Client) Chunk(manifest *Manifest, chunk *Chunk) ([]byte, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s%schunks/%d/%d", c.base, routePrefix, manifest.Header.Block, chunk.Index))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, ErrGone
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chunk %d: %s", chunk.Index, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, chunk.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != chunk.Size || hex.EncodeToString(sum[:]) != chunk.SHA256 {
+		return nil, fmt.Errorf("chunk %d: %w", chunk.Index, ErrChunkChecksum)
+	}
+	return data, nil
+}
+
+// Download writes the snapshot of manifest to path, retrying failed chunks.
+// The file only appears at path once every chunk matched.
+func (c *Client) Download(manifest *Manifest, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "sync-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	for _, chunk := range manifest.Chunks {
+		var data []byte
+		for attempt := 1; ; attempt++ {
+			data, err = c.Chunk(manifest, chunk)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrGone) || attempt == chunkAttempts {
+				return err
+			}
+			logrus.Warnf("download chunk %d/%d err: %v, retrying", chunk.Index+1, len(manifest.Chunks), err)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if _, err := tmp.Write(data); err != nil {
+			return err
+		}
+		logrus.Infof("downloaded chunk %d/%d", chunk.Index+1, len(manifest.Chunks))
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,120 @@
+package statesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/snapshot"
+	"sync/atomic"
+	"testing"
+)
+
+func manifestOf(chunks ...string) *Manifest {
+	manifest := &Manifest{Header: &snapshot.Header{Block: 100}}
+	var offset int64
+	for i, data := range chunks {
+		sum := sha256.Sum256([]byte(data))
+		manifest.Chunks = append(manifest.Chunks, &Chunk{Index: i, Offset: offset, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+		offset += int64(len(data))
+	}
+	manifest.FileSize = offset
+	return manifest
+}
+
+func TestChunk(t *testing.T) {
+	manifest := manifestOf("rose")
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+	}{
+		{"match", http.StatusOK, "rose", nil},
+		{"other content", http.StatusOK, "gems", ErrChunkChecksum},
+		{"short", http.StatusOK, "ros", ErrChunkChecksum},
+		{"long", http.StatusOK, "roses", ErrChunkChecksum},
+		{"gone", http.StatusGone, "", ErrGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != routePrefix+"chunks/100/0" {
+					t.Errorf("requested %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			data, err := NewClient(server.URL+"/").Chunk(manifest, manifest.Chunks[0])
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Chunk err = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(data) != tt.body {
+				t.Fatalf("Chunk = %s, want %s", data, tt.body)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	manifest := manifestOf("ro", "se")
+	tests := []struct {
+		name string
+		// serve answers the attempt of the second chunk
+		serve    func(w http.ResponseWriter, attempt int32)
+		attempts int32
+		err      error
+	}{
+		{"retries a corrupt chunk", func(w http.ResponseWriter, attempt int32) {
+			if attempt == 1 {
+				fmt.Fprint(w, "sx")
+				return
+			}
+			fmt.Fprint(w, "se")
+		}, 2, nil},
+		{"stops when gone", func(w http.ResponseWriter, attempt int32) {
+			w.WriteHeader(http.StatusGone)
+		}, 1, ErrGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case routePrefix + "chunks/100/0":
+					fmt.Fprint(w, "ro")
+				case routePrefix + "chunks/100/1":
+					tt.serve(w, atomic.AddInt32(&attempts, 1))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "100.snapshot")
+			err := NewClient(server.URL).Download(manifest, path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Download err = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.attempts {
+				t.Fatalf("chunk fetched %d times, want %d", attempts, tt.attempts)
+			}
+			data, readErr := os.ReadFile(path)
+			if err != nil {
+				if !os.IsNotExist(readErr) {
+					t.Fatalf("failed download left %s", path)
+				}
+				return
+			}
+			if string(data) != "rose" {
+				t.Fatalf("downloaded %s", data)
+			}
+		})
+	}
+}
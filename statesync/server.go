@@ -0,0 +1,205 @@
+// Package statesync lets a new instance start from the latest snapshot of another one:
+// the snapshot file is served in chunks listed with their sha256 in a manifest.
+package statesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"rose-scriptions-open-indexer/snapshot"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultChunkSize = 4 << 20
+
+// Manifest describes the latest snapshot and how to download it
+type Manifest struct {
+	Header    *snapshot.Header
+	ChunkSize int64
+	// FileSize is the size of the whole snapshot file, header included
+	FileSize int64
+	Chunks   []*Chunk
+}
+
+type Chunk struct {
+	Index  int
+	Offset int64
+	Size   int64
+	SHA256 string
+}
+
+// Handler serves the snapshots of dir:
+//
+//	GET /api/v1/sync/manifest                manifest of the latest snapshot
+//	GET /api/v1/sync/chunks/{block}/{index}  a chunk of the snapshot of block
+type Handler struct {
+	dir       *snapshot.Dir
+	chunkSize int64
+
+	lock sync.Mutex
+	// manifests caches the manifest of each snapshot file by path
+	manifests map[string]*cachedManifest
+}
+
+type cachedManifest struct {
+	modTime  time.Time
+	manifest *Manifest
+}
+
+const routePrefix = "/api/v1/sync/"
+
+func NewHandler(dir *snapshot.Dir, chunkSize int64) *Handler {
+	return &Handler{
+		dir:       dir,
+		chunkSize: chunkSize,
+		manifests: make(map[string]*cachedManifest),
+	}
+}
+
+// Register adds the routes to mux-like registrars such as api.Server
+func (h *Handler) Register(handle func(pattern string, handler http.Handler)) {
+	handle(routePrefix, h)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, routePrefix), "/"), "/")
+	switch {
+	case len(params) == 1 && params[0] == "manifest":
+		path, err := h.dir.Latest()
+		if err != nil {
+			logrus.Errorf("list snapshots err: %v", err)
+			writeError(w, http.StatusInternalServerError, "list snapshots failed")
+			return
+		}
+		if path == "" {
+			writeError(w, http.StatusNotFound, "no snapshot")
+			return
+		}
+		manifest, err := h.manifest(path)
+		if err != nil {
+			logrus.Errorf("snapshot manifest %s err: %v", path, err)
+			writeError(w, http.StatusInternalServerError, "read snapshot failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": manifest})
+	case len(params) == 3 && params[0] == "chunks":
+		block, err := strconv.ParseUint(params[1], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid parameter block")
+			return
+		}
+		index, err := strconv.Atoi(params[2])
+		if err != nil || index < 0 {
+			writeError(w, http.StatusBadRequest, "invalid parameter index")
+			return
+		}
+		h.serveChunk(w, h.dir.Path(block), index)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// manifest hashes the chunks of the snapshot at path, once per file
+func (h *Handler) manifest(path string) (*Manifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if cached, ok := h.manifests[path]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.manifest, nil
+	}
+
+	header, err := snapshot.ReadHeader(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	manifest := &Manifest{Header: header, ChunkSize: h.chunkSize}
+	for {
+		hash := sha256.New()
+		n, err := io.CopyN(hash, f, h.chunkSize)
+		if n > 0 {
+			manifest.Chunks = append(manifest.Chunks, &Chunk{
+				Index:  len(manifest.Chunks),
+				Offset: manifest.FileSize,
+				Size:   n,
+				SHA256: hex.EncodeToString(hash.Sum(nil)),
+			})
+			manifest.FileSize += n
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	h.manifests = map[string]*cachedManifest{path: {modTime: info.ModTime(), manifest: manifest}}
+	return manifest, nil
+}
+
+func (h *Handler) serveChunk(w http.ResponseWriter, path string, index int) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// the snapshot was rotated out, the client starts over with a new manifest
+		writeError(w, http.StatusGone, "snapshot no longer available")
+		return
+	} else if err != nil {
+		logrus.Errorf("open snapshot %s err: %v", path, err)
+		writeError(w, http.StatusInternalServerError, "read snapshot failed")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "read snapshot failed")
+		return
+	}
+	offset := int64(index) * h.chunkSize
+	if offset >= info.Size() {
+		writeError(w, http.StatusNotFound, "chunk not found")
+		return
+	}
+	size := h.chunkSize
+	if offset+size > info.Size() {
+		size = info.Size() - offset
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, io.NewSectionReader(f, offset, size)); err != nil {
+		logrus.Warnf("write chunk err: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("write response err: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
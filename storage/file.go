@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	checkpointPrefix = "checkpoint-"
+	checkpointSuffix = ".json.gz"
+)
+
+// FileStore keeps each checkpoint as a gzip'd json file named after its height
+type FileStore struct {
+	dir string
+	// keep is the number of checkpoints kept after a save, 0 keeps all
+	keep int
+}
+
+func NewFileStore(dir string, keep int) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, keep: keep}, nil
+}
+
+func (s *FileStore) path(height uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", checkpointPrefix, height, checkpointSuffix))
+}
+
+func (s *FileStore) Heights() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var heights []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, checkpointPrefix) || !strings.HasSuffix(name, checkpointSuffix) {
+			continue
+		}
+		height, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, checkpointPrefix), checkpointSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+	return heights, nil
+}
+
+func (s *FileStore) Load() (*core.State, error) {
+	heights, err := s.Heights()
+	if err != nil {
+		return nil, err
+	}
+	if len(heights) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.read(heights[len(heights)-1])
+}
+
+func (s *FileStore) LoadAt(height uint64) (*core.State, error) {
+	heights, err := s.Heights()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(heights) - 1; i >= 0; i-- {
+		if heights[i] <= height {
+			return s.read(heights[i])
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *FileStore) read(height uint64) (*core.State, error) {
+	f, err := os.Open(s.path(height))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint %d: %w", height, err)
+	}
+	defer reader.Close()
+
+	var state core.State
+	if err := json.NewDecoder(reader).Decode(&state); err != nil {
+		return nil, fmt.Errorf("read checkpoint %d: %w", height, err)
+	}
+	// older versions only lack the fields added since, newer ones are unknown
+	if state.Version < 1 || state.Version > core.StateVersion {
+		return nil, fmt.Errorf("checkpoint %d has version %d, expected at most %d", height, state.Version, core.StateVersion)
+	}
+	return &state, nil
+}
+
+// Save writes the checkpoint to a temporary file first so a crash never leaves a partial one
+func (s *FileStore) Save(state *core.State) error {
+	tmp, err := os.CreateTemp(s.dir, checkpointPrefix+"*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(writer).Encode(state); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(state.LatestBlockNumber)); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+func (s *FileStore) prune() error {
+	if s.keep <= 0 {
+		return nil
+	}
+	heights, err := s.Heights()
+	if err != nil {
+		return err
+	}
+	for len(heights) > s.keep {
+		if err := os.Remove(s.path(heights[0])); err != nil {
+			return err
+		}
+		heights = heights[1:]
+	}
+	return nil
+}
+
+func (s *FileStore) Truncate(height uint64) error {
+	heights, err := s.Heights()
+	if err != nil {
+		return err
+	}
+	for _, h := range heights {
+		if h > height {
+			if err := os.Remove(s.path(h)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"strconv"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Key prefixes of the leveldb store. Ticks and addresses in keys are length prefixed,
+// sequence numbers big endian so the keys iterate in order.
+const (
+	prefixMeta        = 'm'
+	prefixToken       = 't' // t lowerTick -> token json
+	prefixBalance     = 'b' // b tick owner -> balance
+	prefixList        = 'l' // l hash -> listing json
+	prefixOp          = 'o' // o index -> rrc-20 operation json
+	prefixInscription = 'i' // i index -> inscription json
+	prefixJournal     = 'j' // j tick owner index -> balance change json
+	prefixRoot        = 'r' // r block -> state root json
+)
+
+const (
+	metaVersion           = "version"
+	metaLatest            = "latest"
+	metaInscriptionNumber = "inscription_number"
+	metaImportedTo        = "imported_to"
+	metaInscriptions      = "inscriptions"
+	metaOps               = "ops"
+	metaRoots             = "roots"
+)
+
+// LevelDBStore keeps the latest state in leveldb, one key per entry, so a save only writes what changed
+// since the previous one, in a single atomic batch. SaveChanges only visits the entries changed by the
+// blocks since, Save compares the whole state. It has no history: Heights is the latest height,
+// LoadAt below it finds nothing, Save below it is refused and Truncate below it drops the state.
+type LevelDBStore struct {
+	db *leveldb.DB
+
+	// what the db holds, to write the differences
+	saved        bool
+	version      int
+	latest       uint64
+	inscriptions int
+	ops          int
+	roots        int
+	// values holds the sha256 of the value of each token, balance and listing key
+	values map[string][sha256.Size]byte
+	// journal counts the balance changes of each tick and owner key prefix
+	journal map[string]int
+}
+
+func NewLevelDBStore(dir string) (*LevelDBStore, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &LevelDBStore{db: db}
+	if err := s.scan(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func metaKey(name string) []byte {
+	return append([]byte{prefixMeta}, name...)
+}
+
+func appendString(key []byte, s string) []byte {
+	key = binary.AppendUvarint(key, uint64(len(s)))
+	return append(key, s...)
+}
+
+func appendIndex(key []byte, index uint64) []byte {
+	return binary.BigEndian.AppendUint64(key, index)
+}
+
+func tokenKey(lowerTick string) []byte {
+	return append([]byte{prefixToken}, lowerTick...)
+}
+
+func balanceKey(lowerTick string, owner string) []byte {
+	return append(appendString([]byte{prefixBalance}, lowerTick), owner...)
+}
+
+func listKey(hash string) []byte {
+	return append([]byte{prefixList}, hash...)
+}
+
+func journalPrefix(lowerTick string, owner string) []byte {
+	return appendString(appendString([]byte{prefixJournal}, lowerTick), owner)
+}
+
+// changePrefix groups the journal by the deployed tick lowercased, which is unique per token
+func changePrefix(change *model.BalanceChange) []byte {
+	return journalPrefix(strings.ToLower(change.Tick), change.Address)
+}
+
+func (s *LevelDBStore) getUint(name string) (uint64, bool, error) {
+	value, err := s.db.Get(metaKey(name), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	n, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return n, true, nil
+}
+
+// scan reads what the db holds
+func (s *LevelDBStore) scan() error {
+	s.values = make(map[string][sha256.Size]byte)
+	s.journal = make(map[string]int)
+
+	latest, ok, err := s.getUint(metaLatest)
+	if err != nil || !ok {
+		return err
+	}
+	version, _, err := s.getUint(metaVersion)
+	if err != nil {
+		return err
+	}
+	if version < 1 || version > core.StateVersion {
+		return fmt.Errorf("unsupported state version %d", version)
+	}
+	s.saved, s.version, s.latest = true, int(version), latest
+	for name, dst := range map[string]*int{metaInscriptions: &s.inscriptions, metaOps: &s.ops, metaRoots: &s.roots} {
+		n, _, err := s.getUint(name)
+		if err != nil {
+			return err
+		}
+		*dst = int(n)
+	}
+
+	for _, prefix := range []byte{prefixToken, prefixBalance, prefixList} {
+		iter := s.db.NewIterator(util.BytesPrefix([]byte{prefix}), nil)
+		for iter.Next() {
+			s.values[string(iter.Key())] = sha256.Sum256(iter.Value())
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	iter := s.db.NewIterator(util.BytesPrefix([]byte{prefixJournal}), nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		s.journal[string(key[:len(key)-8])]++
+	}
+	return iter.Error()
+}
+
+func (s *LevelDBStore) Heights() ([]uint64, error) {
+	if !s.saved {
+		return nil, nil
+	}
+	return []uint64{s.latest}, nil
+}
+
+func (s *LevelDBStore) Load() (*core.State, error) {
+	if !s.saved {
+		return nil, ErrNotFound
+	}
+	inscriptionNumber, _, err := s.getUint(metaInscriptionNumber)
+	if err != nil {
+		return nil, err
+	}
+	importedTo, _, err := s.getUint(metaImportedTo)
+	if err != nil {
+		return nil, err
+	}
+	// scan refused newer versions
+	state := &core.State{
+		Version:           s.version,
+		LatestBlockNumber: s.latest,
+		InscriptionNumber: inscriptionNumber,
+		ImportedTo:        importedTo,
+		Tokens:            make(map[string]*model.Token),
+		TokenHolders:      make(map[string]map[string]*model.DDecimal),
+		Balances:          make(map[string]map[string]*model.DDecimal),
+		Lists:             make(map[string]*model.ListedRecord),
+	}
+
+	err = s.each(prefixToken, func(key []byte, value []byte) error {
+		var token model.Token
+		state.Tokens[string(key[1:])] = &token
+		// tokens without holders have no balance keys, the indexer expects their map
+		state.TokenHolders[string(key[1:])] = make(map[string]*model.DDecimal)
+		return json.Unmarshal(value, &token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixBalance, func(key []byte, value []byte) error {
+		size, n := binary.Uvarint(key[1:])
+		if n <= 0 || 1+n+int(size) > len(key) {
+			return fmt.Errorf("invalid balance key %x", key)
+		}
+		lowerTick, owner := string(key[1+n:1+n+int(size)]), string(key[1+n+int(size):])
+		balance := model.NewDecimal()
+		if err := balance.UnmarshalText(value); err != nil {
+			return err
+		}
+		if state.TokenHolders[lowerTick] == nil {
+			state.TokenHolders[lowerTick] = make(map[string]*model.DDecimal)
+		}
+		state.TokenHolders[lowerTick][owner] = balance
+		if state.Balances[owner] == nil {
+			state.Balances[owner] = make(map[string]*model.DDecimal)
+		}
+		state.Balances[owner][lowerTick] = balance
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixList, func(key []byte, value []byte) error {
+		var listRec model.ListedRecord
+		state.Lists[string(key[1:])] = &listRec
+		return json.Unmarshal(value, &listRec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixOp, func(key []byte, value []byte) error {
+		var rrc20 model.RRC20
+		state.Records = append(state.Records, &rrc20)
+		return json.Unmarshal(value, &rrc20)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixInscription, func(key []byte, value []byte) error {
+		var inscription model.Inscription
+		state.Inscriptions = append(state.Inscriptions, &inscription)
+		return json.Unmarshal(value, &inscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixJournal, func(key []byte, value []byte) error {
+		var change model.BalanceChange
+		state.BalanceChanges = append(state.BalanceChanges, &change)
+		return json.Unmarshal(value, &change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.each(prefixRoot, func(key []byte, value []byte) error {
+		var root model.BlockRoot
+		state.BlockRoots = append(state.BlockRoots, &root)
+		return json.Unmarshal(value, &root)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *LevelDBStore) each(prefix byte, fn func(key []byte, value []byte) error) error {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte{prefix}), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			return fmt.Errorf("read %x: %w", iter.Key(), err)
+		}
+	}
+	return iter.Error()
+}
+
+func (s *LevelDBStore) LoadAt(height uint64) (*core.State, error) {
+	if !s.saved || s.latest > height {
+		return nil, ErrNotFound
+	}
+	return s.Load()
+}
+
+// Save writes the differences to the saved state in one batch. A state saved by another StateVersion
+// is replaced, an older state than the saved one is refused with ErrNewerState.
+func (s *LevelDBStore) Save(state *core.State) error {
+	if s.saved && state.LatestBlockNumber < s.latest {
+		return fmt.Errorf("%w: saved %d, saving %d", ErrNewerState, s.latest, state.LatestBlockNumber)
+	}
+	batch := new(leveldb.Batch)
+	base := s
+	if s.saved && s.version != core.StateVersion {
+		if err := s.deleteAll(batch); err != nil {
+			return err
+		}
+		base = &LevelDBStore{values: map[string][sha256.Size]byte{}, journal: map[string]int{}}
+	}
+
+	values := make(map[string][sha256.Size]byte, len(base.values))
+	put := func(key []byte, value []byte) {
+		h := sha256.Sum256(value)
+		values[string(key)] = h
+		if old, ok := base.values[string(key)]; !ok || old != h {
+			batch.Put(key, value)
+		}
+	}
+	for lowerTick, token := range state.Tokens {
+		value, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		put(tokenKey(lowerTick), value)
+	}
+	for lowerTick, holders := range state.TokenHolders {
+		for owner, balance := range holders {
+			value, err := balance.MarshalText()
+			if err != nil {
+				return err
+			}
+			put(balanceKey(lowerTick, owner), value)
+		}
+	}
+	for hash, listRec := range state.Lists {
+		value, err := json.Marshal(listRec)
+		if err != nil {
+			return err
+		}
+		put(listKey(hash), value)
+	}
+	for key := range base.values {
+		if _, ok := values[key]; !ok {
+			batch.Delete([]byte(key))
+		}
+	}
+
+	// the rest is only appended to
+	if err := appendEntries(batch, prefixInscription, base.inscriptions, unsaved(state.Inscriptions, base.inscriptions)); err != nil {
+		return err
+	}
+	if err := appendEntries(batch, prefixOp, base.ops, unsaved(state.Records, base.ops)); err != nil {
+		return err
+	}
+	if err := appendEntries(batch, prefixRoot, base.roots, unsaved(state.BlockRoots, base.roots)); err != nil {
+		return err
+	}
+
+	journal := make(map[string]int, len(base.journal))
+	for _, change := range state.BalanceChanges {
+		prefix := changePrefix(change)
+		i := journal[string(prefix)]
+		journal[string(prefix)] = i + 1
+		if i < base.journal[string(prefix)] {
+			continue
+		}
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		batch.Put(appendIndex(prefix, uint64(i)), data)
+	}
+	// journals the state doesn't have anymore, e.g. under a previous key
+	for prefix, n := range base.journal {
+		if _, ok := journal[prefix]; !ok {
+			for i := 0; i < n; i++ {
+				batch.Delete(appendIndex([]byte(prefix), uint64(i)))
+			}
+		}
+	}
+
+	putMeta(batch, state.LatestBlockNumber, state.InscriptionNumber, state.ImportedTo, len(state.Inscriptions), len(state.Records), len(state.BlockRoots))
+	if err := s.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	s.saved, s.version, s.latest = true, core.StateVersion, state.LatestBlockNumber
+	s.inscriptions, s.ops, s.roots = len(state.Inscriptions), len(state.Records), len(state.BlockRoots)
+	s.values, s.journal = values, journal
+	return nil
+}
+
+// SaveChanges writes the entries changed since the saved state in one batch. It returns ErrFullSave
+// if the changes don't apply to the saved state, the caller saves the whole state instead.
+func (s *LevelDBStore) SaveChanges(changes *core.StateChanges) error {
+	if changes.Full || !s.saved || s.version != core.StateVersion || changes.From != s.latest {
+		return ErrFullSave
+	}
+
+	batch := new(leveldb.Batch)
+	values := make(map[string]*[sha256.Size]byte)
+	put := func(key []byte, value []byte) {
+		h := sha256.Sum256(value)
+		values[string(key)] = &h
+		if old, ok := s.values[string(key)]; !ok || old != h {
+			batch.Put(key, value)
+		}
+	}
+	remove := func(key []byte) {
+		values[string(key)] = nil
+		if _, ok := s.values[string(key)]; ok {
+			batch.Delete(key)
+		}
+	}
+	for lowerTick, token := range changes.Tokens {
+		if token == nil {
+			remove(tokenKey(lowerTick))
+			continue
+		}
+		value, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		put(tokenKey(lowerTick), value)
+	}
+	for lowerTick, holders := range changes.TokenHolders {
+		for owner, balance := range holders {
+			if balance == nil {
+				remove(balanceKey(lowerTick, owner))
+				continue
+			}
+			value, err := balance.MarshalText()
+			if err != nil {
+				return err
+			}
+			put(balanceKey(lowerTick, owner), value)
+		}
+	}
+	for hash, listRec := range changes.Lists {
+		if listRec == nil {
+			remove(listKey(hash))
+			continue
+		}
+		value, err := json.Marshal(listRec)
+		if err != nil {
+			return err
+		}
+		put(listKey(hash), value)
+	}
+
+	if err := appendEntries(batch, prefixInscription, s.inscriptions, changes.Inscriptions); err != nil {
+		return err
+	}
+	if err := appendEntries(batch, prefixOp, s.ops, changes.Records); err != nil {
+		return err
+	}
+	if err := appendEntries(batch, prefixRoot, s.roots, changes.BlockRoots); err != nil {
+		return err
+	}
+
+	added := make(map[string]int)
+	for _, change := range changes.BalanceChanges {
+		prefix := changePrefix(change)
+		i := s.journal[string(prefix)] + added[string(prefix)]
+		added[string(prefix)]++
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		batch.Put(appendIndex(prefix, uint64(i)), data)
+	}
+
+	inscriptions, ops, roots := s.inscriptions+len(changes.Inscriptions), s.ops+len(changes.Records), s.roots+len(changes.BlockRoots)
+	putMeta(batch, changes.LatestBlockNumber, changes.InscriptionNumber, changes.ImportedTo, inscriptions, ops, roots)
+	if err := s.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	s.latest = changes.LatestBlockNumber
+	s.inscriptions, s.ops, s.roots = inscriptions, ops, roots
+	for key, h := range values {
+		if h == nil {
+			delete(s.values, key)
+		} else {
+			s.values[key] = *h
+		}
+	}
+	for prefix, n := range added {
+		s.journal[prefix] += n
+	}
+	return nil
+}
+
+// unsaved returns the entries after the first saved ones
+func unsaved[T any](entries []T, saved int) []T {
+	if saved >= len(entries) {
+		return nil
+	}
+	return entries[saved:]
+}
+
+// appendEntries puts the entries under the indexes from first on
+func appendEntries[T any](batch *leveldb.Batch, prefix byte, first int, entries []T) error {
+	for i, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		batch.Put(appendIndex([]byte{prefix}, uint64(first+i)), data)
+	}
+	return nil
+}
+
+func putMeta(batch *leveldb.Batch, latest uint64, inscriptionNumber uint64, importedTo uint64, inscriptions int, ops int, roots int) {
+	for name, n := range map[string]uint64{
+		metaVersion:           uint64(core.StateVersion),
+		metaLatest:            latest,
+		metaInscriptionNumber: inscriptionNumber,
+		metaImportedTo:        importedTo,
+		metaInscriptions:      uint64(inscriptions),
+		metaOps:               uint64(ops),
+		metaRoots:             uint64(roots),
+	} {
+		batch.Put(metaKey(name), []byte(strconv.FormatUint(n, 10)))
+	}
+}
+
+func (s *LevelDBStore) deleteAll(batch *leveldb.Batch) error {
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	return iter.Error()
+}
+
+// Truncate drops the state if it is above height, there are no older ones to fall back to
+func (s *LevelDBStore) Truncate(height uint64) error {
+	if !s.saved || s.latest <= height {
+		return nil
+	}
+	batch := new(leveldb.Batch)
+	if err := s.deleteAll(batch); err != nil {
+		return err
+	}
+	if err := s.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	s.saved, s.latest = false, 0
+	s.inscriptions, s.ops, s.roots = 0, 0, 0
+	s.values, s.journal = make(map[string][sha256.Size]byte), make(map[string]int)
+	return nil
+}
+
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"testing"
+)
+
+func calldata(t *testing.T, call *model.RRC20Call) string {
+	t.Helper()
+	data, code := call.Calldata()
+	if code != model.ValidCodeOK {
+		t.Fatalf("calldata %+v: %d", call, code)
+	}
+	return data
+}
+
+func openLevelDB(t *testing.T) *LevelDBStore {
+	t.Helper()
+	store, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func stateJSON(t *testing.T, state *core.State) string {
+	t.Helper()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLevelDBSaveChanges(t *testing.T) {
+	defer core.ImportState(&core.State{})
+
+	const (
+		alice = "0x00000000000000000000000000000000000000aa"
+		bob   = "0x00000000000000000000000000000000000000BB"
+	)
+	core.ImportState(&core.State{Version: core.StateVersion, LatestBlockNumber: 100, ImportedTo: 100})
+	incremental, full := openLevelDB(t), openLevelDB(t)
+
+	txs := [][]*model.ChainTransaction{
+		{{From: alice, To: alice, Input: calldata(t, &model.RRC20Call{Operation: model.RRC20OperationDeploy, Tick: "rose", Max: "100", Limit: "10"})}},
+		{{From: alice, To: alice, Input: calldata(t, &model.RRC20Call{Operation: model.RRC20OperationMint, Tick: "rose", Amount: "10"})}},
+		{},
+		{
+			{From: alice, To: bob, Input: calldata(t, &model.RRC20Call{Operation: model.RRC20OperationTransfer, Tick: "rose", Amount: "4"})},
+			{From: bob, To: bob, Input: calldata(t, &model.RRC20Call{Operation: model.RRC20OperationMint, Tick: "Rose", Amount: "10"})},
+		},
+	}
+	for i, blockTxs := range txs {
+		number := core.LatestBlockNumber + 1
+		for j, tx := range blockTxs {
+			tx.Id = fmt.Sprintf("0x%064x", number*10+uint64(j))
+			tx.Block, tx.Idx, tx.Timestamp = number, uint32(j), number
+		}
+		block := &model.ChainBlock{Number: number, Hash: fmt.Sprintf("0x%064x", number), Txs: blockTxs, Timestamp: number}
+		if err := core.HandleNewBlock(block); err != nil {
+			t.Fatal(err)
+		}
+
+		err := incremental.SaveChanges(core.ExportChanges())
+		if i == 0 && !errors.Is(err, ErrFullSave) {
+			t.Fatalf("first SaveChanges err = %v, want ErrFullSave", err)
+		}
+		if i > 0 && err != nil {
+			t.Fatalf("block %d: SaveChanges err = %v", number, err)
+		}
+		if errors.Is(err, ErrFullSave) {
+			err = incremental.Save(core.ExportState())
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := full.Save(core.ExportState()); err != nil {
+			t.Fatal(err)
+		}
+
+		want := stateJSON(t, core.ExportState())
+		for name, store := range map[string]*LevelDBStore{"incremental": incremental, "full": full} {
+			state, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := stateJSON(t, state); got != want {
+				t.Fatalf("block %d: %s store loads\n%s\nwant\n%s", number, name, got, want)
+			}
+		}
+	}
+
+	if token, ok := core.QueryToken("rose"); !ok || token.Minted.String() != "20" || token.Holders != 2 {
+		t.Fatalf("token = %+v", token)
+	}
+
+	// changes that don't start at the saved block need a full save
+	core.ExportChanges()
+	if err := incremental.SaveChanges(&core.StateChanges{From: 1}); !errors.Is(err, ErrFullSave) {
+		t.Fatalf("SaveChanges from another block err = %v, want ErrFullSave", err)
+	}
+}
+
+func TestLevelDBRefusesOlderState(t *testing.T) {
+	store := openLevelDB(t)
+	if err := store.Save(&core.State{Version: core.StateVersion, LatestBlockNumber: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&core.State{Version: core.StateVersion, LatestBlockNumber: 5}); !errors.Is(err, ErrNewerState) {
+		t.Fatalf("Save older state err = %v, want ErrNewerState", err)
+	}
+	if heights, _ := store.Heights(); len(heights) != 1 || heights[0] != 10 {
+		t.Fatalf("heights = %v", heights)
+	}
+	if err := store.Truncate(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&core.State{Version: core.StateVersion, LatestBlockNumber: 5}); err != nil {
+		t.Fatalf("Save after Truncate err = %v", err)
+	}
+}
+
+func TestLevelDBVersion(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(&core.State{Version: core.StateVersion, LatestBlockNumber: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.db.Put(metaKey(metaVersion), []byte(fmt.Sprint(core.StateVersion+1)), nil); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if store, err := NewLevelDBStore(dir); err == nil {
+		store.Close()
+		t.Fatal("opened a database of a newer state version")
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"rose-scriptions-open-indexer/core"
+)
+
+// Backends accepted by Open
+const (
+	BackendFile    = "file"
+	BackendLevelDB = "leveldb"
+)
+
+var (
+	ErrNotFound = errors.New("no saved state")
+	// ErrFullSave is returned by SaveChanges when the changes don't apply to the saved state
+	ErrFullSave = errors.New("changes need a full save")
+	// ErrNewerState is returned by stores without history when saving a state older than the saved one
+	ErrNewerState = errors.New("a newer state is saved, truncate it first")
+)
+
+// Store persists checkpoints of the indexed state
+type Store interface {
+	// Load returns the most recent state, ErrNotFound if nothing was saved
+	Load() (*core.State, error)
+	// LoadAt returns the most recent state at or below height, ErrNotFound if there is none
+	LoadAt(height uint64) (*core.State, error)
+	// Save stores the state as the checkpoint of its LatestBlockNumber. Stores without history
+	// return ErrNewerState rather than replace a newer state, Truncate drops it explicitly.
+	Save(state *core.State) error
+	// Truncate drops the checkpoints above height
+	Truncate(height uint64) error
+	// Heights lists the saved heights in ascending order
+	Heights() ([]uint64, error)
+	Close() error
+}
+
+// ChangeStore is a Store that also saves a checkpoint from the entries changed since the previous one
+type ChangeStore interface {
+	Store
+	SaveChanges(changes *core.StateChanges) error
+}
+
+// Open opens the store of backend in dir, keep only applies to the file checkpoints
+func Open(backend string, dir string, keep int) (Store, error) {
+	switch backend {
+	case BackendFile:
+		return NewFileStore(dir, keep)
+	case BackendLevelDB:
+		return NewLevelDBStore(dir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %s", backend)
+	}
+}
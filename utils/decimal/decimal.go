@@ -12,6 +12,26 @@ const MAX_PRECISION = 18
 
 var precisionFactor = new(big.Int).Exp(big.NewInt(10), big.NewInt(MAX_PRECISION), nil)
 
+// maxValue is the largest scaled value, 2^256 - 1
+var maxValue = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+var (
+	ErrOverflow       = errors.New("decimal overflow")
+	ErrDivisionByZero = errors.New("decimal division by zero")
+)
+
+// RoundingMode selects how results are rounded to the available decimal places
+type RoundingMode int
+
+const (
+	// RoundDown truncates towards zero
+	RoundDown RoundingMode = iota
+	// RoundHalfEven rounds to the nearest value, ties to the even one
+	RoundHalfEven
+	// RoundUp rounds away from zero
+	RoundUp
+)
+
 // Decimal represents a fixed-point decimal number with 18 decimal places
 type Decimal struct {
 	value *big.Int
@@ -142,11 +162,14 @@ func (d *Decimal) Sub(other *Decimal) *Decimal {
 	return &Decimal{value: value}
 }
 
+// Mul multiplies two Decimal instances and returns a new Decimal instance,
+// the result is truncated to 18 decimal places
 func (d *Decimal) Mul(other *Decimal) *Decimal {
-	if d == nil && other == nil {
-		value := new(big.Int).SetUint64(0)
-		return &Decimal{value: value}
-	}
+	return d.MulRound(other, RoundDown)
+}
+
+// MulRound multiplies two Decimal instances and rounds the result to 18 decimal places with mode
+func (d *Decimal) MulRound(other *Decimal, mode RoundingMode) *Decimal {
 	if d == nil || d.Sign() == 0 {
 		value := new(big.Int).SetUint64(0)
 		return &Decimal{value: value}
@@ -157,24 +180,105 @@ func (d *Decimal) Mul(other *Decimal) *Decimal {
 	}
 
 	value := new(big.Int).Mul(d.value, other.value)
-	return &Decimal{value: value}
+	return &Decimal{value: divRound(value, precisionFactor, mode)}
 }
 
+// Quo divides two Decimal instances and returns a new Decimal instance,
+// the result is truncated to 18 decimal places. Like integer division it panics
+// when other is zero, use CheckedQuo to get ErrDivisionByZero instead
 func (d *Decimal) Quo(other *Decimal) *Decimal {
-	if d == nil && other == nil {
-		value := new(big.Int).SetUint64(0)
-		return &Decimal{value: value}
+	return d.QuoRound(other, RoundDown)
+}
+
+// QuoRound divides two Decimal instances and rounds the result to 18 decimal places with mode,
+// it panics when other is zero
+func (d *Decimal) QuoRound(other *Decimal, mode RoundingMode) *Decimal {
+	if other.Sign() == 0 {
+		panic(ErrDivisionByZero)
 	}
-	if other == nil || other.Sign() == 0 {
-		value := new(big.Int).Set(d.value)
+	if d == nil || d.Sign() == 0 {
+		value := new(big.Int).SetUint64(0)
 		return &Decimal{value: value}
 	}
-	if d == nil || d.Sign() == 0 {
+	value := new(big.Int).Mul(d.value, precisionFactor)
+	return &Decimal{value: divRound(value, other.value, mode)}
+}
+
+// RoundTo rounds d to precision decimal places with mode, precision is clamped to [0, 18]
+func (d *Decimal) RoundTo(precision int, mode RoundingMode) *Decimal {
+	if d == nil {
 		value := new(big.Int).SetUint64(0)
 		return &Decimal{value: value}
 	}
-	value := new(big.Int).Quo(d.value, other.value)
-	return &Decimal{value: value}
+	if precision >= MAX_PRECISION {
+		return NewCopy(d)
+	}
+	if precision < 0 {
+		precision = 0
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MAX_PRECISION-precision)), nil)
+	value := divRound(d.value, factor, mode)
+	return &Decimal{value: value.Mul(value, factor)}
+}
+
+// CheckedAdd is Add returning ErrOverflow when the result is out of range
+func (d *Decimal) CheckedAdd(other *Decimal) (*Decimal, error) {
+	return checked(d.Add(other))
+}
+
+// CheckedSub is Sub returning ErrOverflow when the result is out of range
+func (d *Decimal) CheckedSub(other *Decimal) (*Decimal, error) {
+	return checked(d.Sub(other))
+}
+
+// CheckedMul is MulRound returning ErrOverflow when the result is out of range
+func (d *Decimal) CheckedMul(other *Decimal, mode RoundingMode) (*Decimal, error) {
+	return checked(d.MulRound(other, mode))
+}
+
+// CheckedQuo is QuoRound returning ErrDivisionByZero instead of dividing by zero,
+// and ErrOverflow when the result is out of range
+func (d *Decimal) CheckedQuo(other *Decimal, mode RoundingMode) (*Decimal, error) {
+	if other.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return checked(d.QuoRound(other, mode))
+}
+
+// IsOverflow reports whether the scaled value does not fit in 256 bits
+func (d *Decimal) IsOverflow() bool {
+	if d == nil {
+		return false
+	}
+	return d.value.CmpAbs(maxValue) > 0
+}
+
+func checked(d *Decimal) (*Decimal, error) {
+	if d.IsOverflow() {
+		return nil, ErrOverflow
+	}
+	return d, nil
+}
+
+// divRound returns n / m rounded with mode
+func divRound(n *big.Int, m *big.Int, mode RoundingMode) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(n, m, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// the direction away from zero
+	direction := int64(n.Sign() * m.Sign())
+	switch mode {
+	case RoundUp:
+		quotient.Add(quotient, big.NewInt(direction))
+	case RoundHalfEven:
+		half := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).CmpAbs(m)
+		if half > 0 || (half == 0 && quotient.Bit(0) == 1) {
+			quotient.Add(quotient, big.NewInt(direction))
+		}
+	}
+	return quotient
 }
 
 func (d *Decimal) Cmp(other *Decimal) int {
@@ -210,15 +314,109 @@ func (d *Decimal) IsOverflowUint64() bool {
 	return false
 }
 
+// IsOverflowInt64 reports whether the integer part of d does not fit in an int64
+func (d *Decimal) IsOverflowInt64() bool {
+	if d == nil {
+		return false
+	}
+	return !new(big.Int).Quo(d.value, precisionFactor).IsInt64()
+}
+
+// IsOverflowScaledUint64 reports whether d scaled to precision decimal places does not fit in a uint64
+func (d *Decimal) IsOverflowScaledUint64(precision int) bool {
+	if d == nil {
+		return false
+	}
+	return !d.truncatedScaledInt(precision).IsUint64()
+}
+
+// IsOverflowScaledInt64 reports whether d scaled to precision decimal places does not fit in an int64
+func (d *Decimal) IsOverflowScaledInt64(precision int) bool {
+	if d == nil {
+		return false
+	}
+	return !d.truncatedScaledInt(precision).IsInt64()
+}
+
+// Float64 returns the nearest float64 value of d
 func (d *Decimal) Float64() float64 {
 	if d == nil {
 		return 0
 	}
-	value := new(big.Int).Abs(d.value)
-	quotient, remainder := new(big.Int).QuoRem(value, precisionFactor, new(big.Int))
-	f := float64(quotient.Uint64()) + float64(remainder.Uint64())/math.MaxFloat64
-	if d.value.Sign() < 0 {
-		return -f
-	}
+	value := new(big.Float).SetPrec(512).SetInt(d.value)
+	value.Quo(value, new(big.Float).SetPrec(512).SetInt(precisionFactor))
+	f, _ := value.Float64()
 	return f
 }
+
+// Rat returns the exact value of d as a fraction
+func (d *Decimal) Rat() *big.Rat {
+	if d == nil {
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFrac(d.value, precisionFactor)
+}
+
+// NewFromScaledInt creates a Decimal instance from an integer scaled by 10^precision,
+// e.g. NewFromScaledInt(1500, 3) is 1.5
+func NewFromScaledInt(value *big.Int, precision int) (*Decimal, error) {
+	if precision < 0 || precision > MAX_PRECISION {
+		return nil, fmt.Errorf("invalid precision: %d", precision)
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MAX_PRECISION-precision)), nil)
+	return &Decimal{value: new(big.Int).Mul(value, factor)}, nil
+}
+
+// ScaledInt returns d as an integer scaled by 10^precision, the wei-style representation of
+// an amount with precision decimals. It fails if d has more than precision decimal places
+func (d *Decimal) ScaledInt(precision int) (*big.Int, error) {
+	if precision < 0 || precision > MAX_PRECISION {
+		return nil, fmt.Errorf("invalid precision: %d", precision)
+	}
+	if d == nil {
+		return new(big.Int), nil
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MAX_PRECISION-precision)), nil)
+	quotient, remainder := new(big.Int).QuoRem(d.value, factor, new(big.Int))
+	if remainder.Sign() != 0 {
+		return nil, fmt.Errorf("decimal %s exceeds precision %d", d.String(), precision)
+	}
+	return quotient, nil
+}
+
+// StringFixed returns d truncated to precision decimal places and padded with zeros,
+// e.g. 1.5 with precision 3 is "1.500"
+func (d *Decimal) StringFixed(precision int) string {
+	if precision > MAX_PRECISION {
+		precision = MAX_PRECISION
+	}
+	if precision < 0 {
+		precision = 0
+	}
+	value := d.truncatedScaledInt(precision)
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+		value.Abs(value)
+	}
+	if precision == 0 {
+		return sign + value.String()
+	}
+	digits := fmt.Sprintf("%0*s", precision+1, value.String())
+	return sign + digits[:len(digits)-precision] + "." + digits[len(digits)-precision:]
+}
+
+// truncatedScaledInt returns d scaled to precision decimal places, truncating the rest
+func (d *Decimal) truncatedScaledInt(precision int) *big.Int {
+	if d == nil {
+		return new(big.Int)
+	}
+	if precision > MAX_PRECISION {
+		precision = MAX_PRECISION
+	}
+	if precision < 0 {
+		precision = 0
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MAX_PRECISION-precision)), nil)
+	return new(big.Int).Quo(d.value, factor)
+}
@@ -0,0 +1,208 @@
+package decimal
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func mustDecimal(t *testing.T, s string) *Decimal {
+	t.Helper()
+	d, _, err := NewFromString(s)
+	if err != nil {
+		t.Fatalf("NewFromString(%q): %v", s, err)
+	}
+	return d
+}
+
+func TestNewFromString(t *testing.T) {
+	tests := []struct {
+		in        string
+		out       string
+		precision int
+		err       bool
+	}{
+		{"0", "0", 0, false},
+		{"1", "1", 0, false},
+		{"-1.5", "-1.5", 1, false},
+		// the sign of a zero integer part is lost, amounts have always been parsed this way
+		{"-0.5", "0.5", 1, false},
+		{"1.500", "1.5", 3, false},
+		{"0.000000000000000001", "0.000000000000000001", 18, false},
+		{"0.0000000000000000001", "", 0, true},
+		{"", "", 0, true},
+		{"+1", "", 0, true},
+		{".5", "", 0, true},
+		{"1.", "", 0, true},
+		{"1.-5", "", 0, true},
+		{"1.2.3", "", 0, true},
+		{"abc", "", 0, true},
+	}
+	for _, tt := range tests {
+		d, precision, err := NewFromString(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("NewFromString(%q) err = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if d.String() != tt.out || precision != tt.precision {
+			t.Errorf("NewFromString(%q) = %s, %d, want %s, %d", tt.in, d, precision, tt.out, tt.precision)
+		}
+	}
+}
+
+func TestMulRound(t *testing.T) {
+	tests := []struct {
+		a, b string
+		mode RoundingMode
+		want string
+	}{
+		{"2", "3", RoundDown, "6"},
+		{"1.5", "1.5", RoundDown, "2.25"},
+		{"-1.5", "2", RoundDown, "-3"},
+		{"0", "5", RoundDown, "0"},
+		// 0.000000000000000001 * 0.5 is below the last decimal place
+		{"0.000000000000000001", "0.5", RoundDown, "0"},
+		{"0.000000000000000001", "0.5", RoundHalfEven, "0"},
+		{"0.000000000000000003", "0.5", RoundHalfEven, "0.000000000000000002"},
+		{"0.000000000000000001", "0.5", RoundUp, "0.000000000000000001"},
+		{"-1.000000000000000001", "0.5", RoundUp, "-0.500000000000000001"},
+		{"-1.000000000000000001", "0.5", RoundDown, "-0.5"},
+		{"0.000000000000000001", "0.6", RoundHalfEven, "0.000000000000000001"},
+	}
+	for _, tt := range tests {
+		got := mustDecimal(t, tt.a).MulRound(mustDecimal(t, tt.b), tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s * %s mode %d = %s, want %s", tt.a, tt.b, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestQuoRound(t *testing.T) {
+	tests := []struct {
+		a, b string
+		mode RoundingMode
+		want string
+	}{
+		{"6", "3", RoundDown, "2"},
+		{"1", "4", RoundDown, "0.25"},
+		{"1", "3", RoundDown, "0.333333333333333333"},
+		{"2", "3", RoundDown, "0.666666666666666666"},
+		{"2", "3", RoundHalfEven, "0.666666666666666667"},
+		{"1", "3", RoundUp, "0.333333333333333334"},
+		{"-1", "3", RoundDown, "-0.333333333333333333"},
+		{"-1", "3", RoundUp, "-0.333333333333333334"},
+		{"-2", "3", RoundHalfEven, "-0.666666666666666667"},
+		{"0", "3", RoundUp, "0"},
+		// ties go to the even last digit
+		{"0.000000000000000001", "2", RoundHalfEven, "0"},
+		{"0.000000000000000003", "2", RoundHalfEven, "0.000000000000000002"},
+	}
+	for _, tt := range tests {
+		got := mustDecimal(t, tt.a).QuoRound(mustDecimal(t, tt.b), tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s / %s mode %d = %s, want %s", tt.a, tt.b, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestQuoByZero(t *testing.T) {
+	for _, a := range []string{"0", "1"} {
+		if _, err := mustDecimal(t, a).CheckedQuo(New(), RoundDown); !errors.Is(err, ErrDivisionByZero) {
+			t.Errorf("CheckedQuo(%s, 0) err = %v, want ErrDivisionByZero", a, err)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != ErrDivisionByZero {
+					t.Errorf("Quo(%s, 0) recovered %v, want ErrDivisionByZero", a, r)
+				}
+			}()
+			mustDecimal(t, a).Quo(New())
+		}()
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		in        string
+		precision int
+		mode      RoundingMode
+		want      string
+	}{
+		{"1.25", 1, RoundDown, "1.2"},
+		{"1.25", 1, RoundHalfEven, "1.2"},
+		{"1.35", 1, RoundHalfEven, "1.4"},
+		{"1.21", 1, RoundUp, "1.3"},
+		{"-1.21", 1, RoundUp, "-1.3"},
+		{"-1.29", 1, RoundDown, "-1.2"},
+		{"1.5", 0, RoundHalfEven, "2"},
+		{"2.5", 0, RoundHalfEven, "2"},
+		{"1.5", -1, RoundDown, "1"},
+		{"1.123", 18, RoundDown, "1.123"},
+	}
+	for _, tt := range tests {
+		got := mustDecimal(t, tt.in).RoundTo(tt.precision, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("RoundTo(%s, %d, %d) = %s, want %s", tt.in, tt.precision, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestChecked(t *testing.T) {
+	max := &Decimal{value: new(big.Int).Set(maxValue)}
+	one := &Decimal{value: big.NewInt(1)}
+	if _, err := max.CheckedAdd(one); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max + 1 err = %v, want ErrOverflow", err)
+	}
+	if _, err := max.Sub(max).Sub(max).CheckedSub(one); !errors.Is(err, ErrOverflow) {
+		t.Errorf("-max - 1 err = %v, want ErrOverflow", err)
+	}
+	if _, err := max.CheckedMul(mustDecimal(t, "2"), RoundDown); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max * 2 err = %v, want ErrOverflow", err)
+	}
+	if got, err := max.CheckedSub(one); err != nil || got.Cmp(max) >= 0 {
+		t.Errorf("max - 1 = %v, %v", got, err)
+	}
+	if _, err := max.CheckedQuo(mustDecimal(t, "0.5"), RoundDown); !errors.Is(err, ErrOverflow) {
+		t.Errorf("max / 0.5 err = %v, want ErrOverflow", err)
+	}
+}
+
+func TestScaledInt(t *testing.T) {
+	tests := []struct {
+		in        string
+		precision int
+		want      string
+		fixed     string
+		err       bool
+	}{
+		{"1.5", 3, "1500", "1.500", false},
+		{"-1.5", 1, "-15", "-1.5", false},
+		{"1.25", 1, "", "1.2", true},
+		{"0.000000000000000001", 18, "1", "0.000000000000000001", false},
+		{"12", 0, "12", "12", false},
+	}
+	for _, tt := range tests {
+		d := mustDecimal(t, tt.in)
+		if fixed := d.StringFixed(tt.precision); fixed != tt.fixed {
+			t.Errorf("StringFixed(%s, %d) = %s, want %s", tt.in, tt.precision, fixed, tt.fixed)
+		}
+		scaled, err := d.ScaledInt(tt.precision)
+		if (err != nil) != tt.err {
+			t.Errorf("ScaledInt(%s, %d) err = %v, want error %v", tt.in, tt.precision, err, tt.err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if scaled.String() != tt.want {
+			t.Errorf("ScaledInt(%s, %d) = %s, want %s", tt.in, tt.precision, scaled, tt.want)
+		}
+		back, err := NewFromScaledInt(scaled, tt.precision)
+		if err != nil || back.Cmp(d) != 0 {
+			t.Errorf("NewFromScaledInt(%s, %d) = %v, %v, want %s", scaled, tt.precision, back, err, tt.in)
+		}
+	}
+}
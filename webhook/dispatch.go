@@ -0,0 +1,427 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"rose-scriptions-open-indexer/core"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 10 * time.Minute
+
+	queueSize      = 10000
+	workers        = 4
+	requestTimeout = 10 * time.Second
+	// maxLogs is the number of deliveries kept per webhook
+	maxLogs = 100
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Delivery is the log entry of one payload sent to a webhook
+type Delivery struct {
+	ID         string
+	WebhookID  string
+	Event      EventType
+	Block      uint64
+	Status     DeliveryStatus
+	Attempts   int
+	StatusCode int    `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	CreatedAt  int64
+	UpdatedAt  int64
+	// Payload is only written to the delivery log while pending and to the dead-letter file
+	Payload json.RawMessage `json:",omitempty"`
+}
+
+// Dispatcher posts the payloads of committed blocks to the subscribed webhooks,
+// retrying failures with exponential backoff. Deliveries that exhaust their attempts
+// are appended as json lines to the dead-letter file.
+// Every change of a delivery is appended to the delivery log, which is read back at the start
+// to serve the logs and retry the pending deliveries.
+type Dispatcher struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	store          *Store
+	client         *http.Client
+	deadLetterPath string
+	queue          chan *job
+
+	lock sync.Mutex
+	logs map[string][]*Delivery
+	// ids holds the logged deliveries by id, logFile is the delivery log
+	ids     map[string]*Delivery
+	logFile *os.File
+	// deadLock serializes writes to the dead-letter file
+	deadLock sync.Mutex
+}
+
+type job struct {
+	delivery *Delivery
+	payload  []byte
+}
+
+// NewDispatcher loads the delivery log at logPath, the pending deliveries are retried after InitialBackoff
+func NewDispatcher(store *Store, deadLetterPath string, logPath string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+		store:          store,
+		deadLetterPath: deadLetterPath,
+		queue:          make(chan *job, queueSize),
+		logs:           make(map[string][]*Delivery),
+		ids:            make(map[string]*Delivery),
+	}
+	pending, err := d.loadLog(logPath)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   requestTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if d.store.AllowPrivate {
+				return nil
+			}
+			return dialControl(network, address, c)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	d.client = &http.Client{Timeout: requestTimeout, Transport: transport}
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	for _, j := range pending {
+		d.retry(j, d.InitialBackoff)
+	}
+	core.OnBlockCommitted(d.handleBlock)
+	return d, nil
+}
+
+// loadLog reads the delivery log, keeping the last maxLogs deliveries of the existing webhooks,
+// and rewrites it with them. It returns the pending deliveries.
+func (d *Dispatcher) loadLog(path string) ([]*job, error) {
+	var logged []*Delivery
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			var delivery Delivery
+			if err := json.Unmarshal(scanner.Bytes(), &delivery); err != nil {
+				// the last line may be cut short by a crash
+				logrus.Warnf("skip delivery log line: %v", err)
+				continue
+			}
+			if existing, ok := d.ids[delivery.ID]; ok {
+				*existing = delivery
+				continue
+			}
+			if _, ok := d.store.Get(delivery.WebhookID); !ok {
+				continue
+			}
+			d.ids[delivery.ID] = &delivery
+			logged = append(logged, &delivery)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var pending []*job
+	var buf bytes.Buffer
+	for _, delivery := range logged {
+		d.log(delivery)
+	}
+	for _, logs := range d.logs {
+		for _, delivery := range logs {
+			line, err := json.Marshal(delivery)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(append(line, '\n'))
+			if delivery.Status == DeliveryPending {
+				pending = append(pending, &job{delivery: delivery, payload: delivery.Payload})
+			}
+			delivery.Payload = nil
+		}
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	d.logFile, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// deliveryID identifies a payload of a webhook. It is the same when a block is handled again,
+// e.g. replayed after a restart, so the payload is not sent twice and receivers can drop duplicates.
+func deliveryID(webhookID string, payload *Payload) string {
+	key := strings.Join([]string{
+		webhookID,
+		strconv.FormatUint(payload.Block, 10),
+		payload.TxHash,
+		string(payload.Event),
+		payload.Tick,
+		payload.Address,
+	}, "|")
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:16])
+}
+
+// Sign returns the X-Webhook-Signature value for a payload sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the X-Webhook-Signature of a received payload
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Deliveries returns the most recent deliveries of a webhook, newest first
+func (d *Dispatcher) Deliveries(webhookID string) []*Delivery {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	logs := d.logs[webhookID]
+	res := make([]*Delivery, 0, len(logs))
+	for i := len(logs) - 1; i >= 0; i-- {
+		copied := *logs[i]
+		res = append(res, &copied)
+	}
+	return res
+}
+
+// handleBlock runs on the indexing goroutine, it only queues the deliveries
+func (d *Dispatcher) handleBlock(result *core.BlockResult) {
+	for _, sub := range d.store.List() {
+		for _, payload := range sub.match(result) {
+			payload.DeliveryID = deliveryID(sub.ID, payload)
+			payload.WebhookID = sub.ID
+			if d.logged(payload.DeliveryID) {
+				continue
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				logrus.Errorf("marshal webhook payload err: %v", err)
+				continue
+			}
+
+			now := time.Now().Unix()
+			delivery := &Delivery{
+				ID:        payload.DeliveryID,
+				WebhookID: sub.ID,
+				Event:     payload.Event,
+				Block:     payload.Block,
+				Status:    DeliveryPending,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			j := &job{delivery: delivery, payload: body}
+			d.lock.Lock()
+			d.log(delivery)
+			d.persist(j)
+			d.lock.Unlock()
+
+			select {
+			case d.queue <- j:
+			default:
+				d.update(j, func(delivery *Delivery) {
+					delivery.Error = "delivery queue full"
+				})
+				d.deadLetter(j)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	for j := range d.queue {
+		d.attempt(j)
+	}
+}
+
+func (d *Dispatcher) attempt(j *job) {
+	sub, ok := d.store.Get(j.delivery.WebhookID)
+	if !ok {
+		// deleted while retrying
+		return
+	}
+
+	statusCode, err := d.post(sub, j.delivery.Event, j.payload)
+	var attempts int
+	d.update(j, func(delivery *Delivery) {
+		delivery.Attempts++
+		delivery.StatusCode = statusCode
+		delivery.Error = ""
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Status = DeliveryDelivered
+		}
+		attempts = delivery.Attempts
+	})
+	if err == nil {
+		return
+	}
+
+	if attempts >= d.MaxAttempts {
+		logrus.Warnf("webhook %s delivery %s failed %d times: %v", sub.ID, j.delivery.ID, attempts, err)
+		d.deadLetter(j)
+		return
+	}
+
+	backoff := d.InitialBackoff << (attempts - 1)
+	if backoff > d.MaxBackoff || backoff <= 0 {
+		backoff = d.MaxBackoff
+	}
+	d.retry(j, backoff)
+}
+
+func (d *Dispatcher) retry(j *job, backoff time.Duration) {
+	time.AfterFunc(backoff, func() {
+		d.queue <- j
+	})
+}
+
+func (d *Dispatcher) post(sub *Subscription, event EventType, body []byte) (int, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", sub.ID)
+	req.Header.Set("X-Webhook-Event", string(event))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) logged(id string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	_, ok := d.ids[id]
+	return ok
+}
+
+// log adds a delivery to the logs of its webhook, the caller holds the lock
+func (d *Dispatcher) log(delivery *Delivery) {
+	d.ids[delivery.ID] = delivery
+	logs := append(d.logs[delivery.WebhookID], delivery)
+	if len(logs) > maxLogs {
+		for _, dropped := range logs[:len(logs)-maxLogs] {
+			delete(d.ids, dropped.ID)
+		}
+		logs = logs[len(logs)-maxLogs:]
+	}
+	d.logs[delivery.WebhookID] = logs
+}
+
+// persist appends the delivery to the delivery log, with its payload while it is pending.
+// The caller holds the lock.
+func (d *Dispatcher) persist(j *job) {
+	record := *j.delivery
+	if record.Status == DeliveryPending {
+		record.Payload = j.payload
+	}
+	line, err := json.Marshal(&record)
+	if err != nil {
+		logrus.Errorf("marshal delivery log err: %v", err)
+		return
+	}
+	if _, err := d.logFile.Write(append(line, '\n')); err != nil {
+		logrus.Errorf("write delivery log err: %v", err)
+	}
+}
+
+func (d *Dispatcher) update(j *job, fn func(*Delivery)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	fn(j.delivery)
+	j.delivery.UpdatedAt = time.Now().Unix()
+	d.persist(j)
+}
+
+func (d *Dispatcher) deadLetter(j *job) {
+	d.update(j, func(delivery *Delivery) {
+		delivery.Status = DeliveryDead
+	})
+
+	d.lock.Lock()
+	record := *j.delivery
+	d.lock.Unlock()
+	record.Payload = j.payload
+
+	line, err := json.Marshal(&record)
+	if err != nil {
+		logrus.Errorf("marshal dead letter err: %v", err)
+		return
+	}
+
+	d.deadLock.Lock()
+	defer d.deadLock.Unlock()
+
+	f, err := os.OpenFile(d.deadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.Errorf("open dead letter file err: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logrus.Errorf("write dead letter err: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeliveryID(t *testing.T) {
+	payload := &Payload{Event: EventReceived, Block: 10, TxHash: "0x01", Tick: "rose", Address: "0xaa"}
+	id := deliveryID("a", payload)
+	if len(id) != 32 || deliveryID("a", payload) != id {
+		t.Fatalf("deliveryID = %s, not stable", id)
+	}
+	other := *payload
+	other.Address = "0xbb"
+	for name, got := range map[string]string{"webhook": deliveryID("b", payload), "address": deliveryID("a", &other)} {
+		if got == id {
+			t.Errorf("deliveryID of another %s = %s, want a different one", name, got)
+		}
+	}
+}
+
+func TestDispatcherRetriesLoggedDeliveries(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "webhooks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.AllowPrivate = true
+	sub, err := store.Create(&Subscription{URL: server.URL, Addresses: []string{"0xaa"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []byte
+	for _, delivery := range []*Delivery{
+		{ID: "pending", WebhookID: sub.ID, Status: DeliveryPending, Payload: json.RawMessage(`{"Block":1}`)},
+		{ID: "done", WebhookID: sub.ID, Status: DeliveryPending, Payload: json.RawMessage(`{"Block":2}`)},
+		{ID: "done", WebhookID: sub.ID, Status: DeliveryDelivered, Attempts: 1},
+		{ID: "deleted", WebhookID: "deleted", Status: DeliveryPending, Payload: json.RawMessage(`{"Block":3}`)},
+	} {
+		line, _ := json.Marshal(delivery)
+		lines = append(append(lines, line...), '\n')
+	}
+	// a line cut short by a crash
+	lines = append(lines, `{"ID":"cut`...)
+	logPath := filepath.Join(dir, "deliveries.jsonl")
+	if err := os.WriteFile(logPath, lines, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDispatcher(store, filepath.Join(dir, "dead.jsonl"), logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries := d.Deliveries(sub.ID); len(deliveries) != 2 || deliveries[0].ID != "done" || deliveries[0].Status != DeliveryDelivered {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+
+	select {
+	case body := <-received:
+		if body != `{"Block":1}` {
+			t.Fatalf("received %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending delivery not retried")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Deliveries(sub.ID)[1].Status != DeliveryDelivered {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery not marked delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the log read again has nothing left to retry
+	reloaded, err := NewDispatcher(store, filepath.Join(dir, "dead.jsonl"), logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range reloaded.Deliveries(sub.ID) {
+		if delivery.Status != DeliveryDelivered {
+			t.Fatalf("reloaded delivery %+v", delivery)
+		}
+	}
+}
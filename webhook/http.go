@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler serves the management API:
+//
+//	GET    /api/v1/webhooks                    list
+//	POST   /api/v1/webhooks                    create, the response is the only one containing the secret
+//	GET    /api/v1/webhooks/{id}               detail
+//	DELETE /api/v1/webhooks/{id}               delete
+//	GET    /api/v1/webhooks/{id}/deliveries    recent deliveries, newest first
+//
+// Requests need an "Authorization: Bearer <adminToken>" header, without a token every request is refused.
+type Handler struct {
+	store      *Store
+	dispatcher *Dispatcher
+	adminToken string
+}
+
+const routePrefix = "/api/v1/webhooks"
+
+func NewHandler(store *Store, dispatcher *Dispatcher, adminToken string) *Handler {
+	return &Handler{
+		store:      store,
+		dispatcher: dispatcher,
+		adminToken: adminToken,
+	}
+}
+
+// Register adds the routes to mux-like registrars such as api.Server
+func (h *Handler) Register(handle func(pattern string, handler http.Handler)) {
+	handle(routePrefix, h)
+	handle(routePrefix+"/", h)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, routePrefix), "/")
+	var params []string
+	if rest != "" {
+		params = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(params) == 0 && r.Method == http.MethodGet:
+		subs := h.store.List()
+		for _, sub := range subs {
+			sub.Secret = ""
+		}
+		writeJSON(w, http.StatusOK, subs)
+	case len(params) == 0 && r.Method == http.MethodPost:
+		var sub Subscription
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid webhook")
+			return
+		}
+		created, err := h.store.Create(&sub)
+		if errors.Is(err, ErrInvalidURL) || errors.Is(err, ErrPrivateTarget) || errors.Is(err, ErrEmptyFilter) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			logrus.Errorf("create webhook err: %v", err)
+			writeError(w, http.StatusInternalServerError, "create webhook failed")
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	case len(params) == 1 && r.Method == http.MethodGet:
+		sub, ok := h.store.Get(params[0])
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound.Error())
+			return
+		}
+		sub.Secret = ""
+		writeJSON(w, http.StatusOK, sub)
+	case len(params) == 1 && r.Method == http.MethodDelete:
+		if err := h.store.Delete(params[0]); errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			logrus.Errorf("delete webhook err: %v", err)
+			writeError(w, http.StatusInternalServerError, "delete webhook failed")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(params) == 2 && params[1] == "deliveries" && r.Method == http.MethodGet:
+		if _, ok := h.store.Get(params[0]); !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, h.dispatcher.Deliveries(params[0]))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("write response err: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+var ErrPrivateTarget = errors.New("webhook url must not point to a loopback, link-local or private address")
+
+// sharedAddressSpace is the carrier-grade NAT range, it isn't covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedIP reports whether ip is an address webhooks must not reach: anything that is not
+// a public unicast address, like the metadata endpoints and services next to the indexer
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkHost returns ErrPrivateTarget if host is or resolves to a blocked address. Hosts that
+// don't resolve are accepted, the dialer checks the address again on every delivery.
+func checkHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedIP(ip) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupIP(context.Background(), "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if blockedIP(ip) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to blocked addresses after name resolution, so neither
+// DNS changes nor redirects reach them
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+		return fmt.Errorf("dial %s: %w", address, ErrPrivateTarget)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := blockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("blockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestCreatePrivateTarget(t *testing.T) {
+	store, err := NewStore(t.TempDir() + "/webhooks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"http://127.0.0.1/hook", "http://localhost:8080/hook", "http://[::1]/hook", "http://169.254.169.254/latest"} {
+		sub := &Subscription{URL: url, Ticks: []string{"rose"}}
+		if _, err := store.Create(sub); !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("Create(%s) err = %v, want ErrPrivateTarget", url, err)
+		}
+	}
+
+	store.AllowPrivate = true
+	if _, err := store.Create(&Subscription{URL: "http://127.0.0.1/hook", Ticks: []string{"rose"}}); err != nil {
+		t.Errorf("Create with AllowPrivate err = %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rose-scriptions-open-indexer/core"
+	"rose-scriptions-open-indexer/core/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventReceived fires when a watched address receives tokens
+	EventReceived EventType = "balance.received"
+	// EventMintedOut fires when a watched tick is minted out
+	EventMintedOut EventType = "token.minted_out"
+)
+
+var (
+	ErrNotFound    = errors.New("webhook not found")
+	ErrInvalidURL  = errors.New("webhook url must be http or https")
+	ErrEmptyFilter = errors.New("webhook must watch at least one address or tick")
+)
+
+// Subscription delivers the events of the watched addresses and ticks to URL.
+// With both addresses and ticks set, received events need to match both.
+type Subscription struct {
+	ID        string
+	URL       string
+	Secret    string `json:",omitempty"`
+	Addresses []string
+	Ticks     []string
+	CreatedAt int64
+}
+
+// Payload is the json body posted to the subscriber
+type Payload struct {
+	DeliveryID string
+	WebhookID  string
+	Event      EventType
+	Block      uint64
+	TxHash     string
+	Tick       string
+	Address    string          `json:",omitempty"`
+	Delta      *model.DDecimal `json:",omitempty"`
+	Balance    *model.DDecimal `json:",omitempty"`
+}
+
+func (s *Subscription) watchesAddress(address string) bool {
+	if len(s.Addresses) == 0 {
+		return true
+	}
+	for _, watched := range s.Addresses {
+		if strings.EqualFold(watched, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) watchesTick(tick string) bool {
+	if len(s.Ticks) == 0 {
+		return true
+	}
+	for _, watched := range s.Ticks {
+		if core.TickKey(watched) == core.TickKey(tick) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the payloads of a committed block for this subscription
+func (s *Subscription) match(result *core.BlockResult) []*Payload {
+	var res []*Payload
+	if len(s.Addresses) > 0 {
+		for _, change := range result.BalanceChanges {
+			if change.Delta.Sign() > 0 && s.watchesAddress(change.Address) && s.watchesTick(change.Tick) {
+				res = append(res, &Payload{
+					Event:   EventReceived,
+					Block:   change.Block,
+					TxHash:  change.TxHash,
+					Tick:    change.Tick,
+					Address: change.Address,
+					Delta:   change.Delta,
+					Balance: change.Balance,
+				})
+			}
+		}
+	}
+	if len(s.Ticks) > 0 {
+		for _, transition := range result.Transitions {
+			if transition.State == model.TokenStateMintedOut && s.watchesTick(transition.Tick) {
+				res = append(res, &Payload{
+					Event:  EventMintedOut,
+					Block:  transition.Block,
+					TxHash: transition.TxHash,
+					Tick:   transition.Tick,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// Store keeps the subscriptions in a json file
+type Store struct {
+	// AllowPrivate accepts urls on loopback, link-local and private addresses and lets the deliveries
+	// reach them, it is set before NewDispatcher
+	AllowPrivate bool
+
+	path          string
+	lock          sync.RWMutex
+	subscriptions map[string]*Subscription
+}
+
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:          path,
+		subscriptions: make(map[string]*Subscription),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []*Subscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, err
+	}
+	for _, sub := range subscriptions {
+		s.subscriptions[sub.ID] = sub
+	}
+	return s, nil
+}
+
+// Create validates and saves a new subscription, a secret is generated if none is given
+func (s *Store) Create(sub *Subscription) (*Subscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if !s.AllowPrivate {
+		if err := checkHost(u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	if len(sub.Addresses) == 0 && len(sub.Ticks) == 0 {
+		return nil, ErrEmptyFilter
+	}
+
+	created := *sub
+	created.ID = randomHex(16)
+	if created.Secret == "" {
+		created.Secret = randomHex(32)
+	}
+	for i, address := range created.Addresses {
+		created.Addresses[i] = strings.ToLower(address)
+	}
+	created.CreatedAt = time.Now().Unix()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.subscriptions[created.ID] = &created
+	if err := s.save(); err != nil {
+		delete(s.subscriptions, created.ID)
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *Store) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.subscriptions, id)
+	if err := s.save(); err != nil {
+		s.subscriptions[id] = sub
+		return err
+	}
+	return nil
+}
+
+func (s *Store) Get(id string) (*Subscription, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *sub
+	return &copied, true
+}
+
+// List returns the subscriptions oldest first
+func (s *Store) List() []*Subscription {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	res := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		copied := *sub
+		res = append(res, &copied)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt != res[j].CreatedAt {
+			return res[i].CreatedAt < res[j].CreatedAt
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// save writes the file atomically, the caller holds the lock
+func (s *Store) save() error {
+	subscriptions := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	data, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}